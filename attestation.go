@@ -0,0 +1,219 @@
+package bcr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxEnvelopeSize bounds the size of an attestation bundle downloaded by
+// [Client.VerifyAttestation].
+const maxEnvelopeSize = 16 << 20
+
+// inTotoPayloadType is the DSSE payload type of in-toto statements.
+const inTotoPayloadType = "application/vnd.in-toto+json"
+
+// Envelope is a DSSE (Dead Simple Signing Envelope) holding a signed
+// in-toto statement, as referenced by attestations.json.
+type Envelope struct {
+	// PayloadType identifies the payload format, normally
+	// "application/vnd.in-toto+json".
+	PayloadType string `json:"payloadType"`
+
+	// Payload is the base64-encoded signed payload.
+	Payload string `json:"payload"`
+
+	// Signatures are the signatures over the payload. Checking them is
+	// left to a [Verifier].
+	Signatures []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is a signature of an [Envelope].
+type EnvelopeSignature struct {
+	// KeyID optionally identifies the signing key.
+	KeyID string `json:"keyid,omitempty"`
+
+	// Sig is the base64-encoded signature.
+	Sig string `json:"sig"`
+}
+
+// Statement is an in-toto statement, the payload of an attestation
+// [Envelope].
+type Statement struct {
+	// Type is the statement type (e.g., "https://in-toto.io/Statement/v1").
+	Type string `json:"_type"`
+
+	// Subject lists the artifacts the statement is about.
+	Subject []StatementSubject `json:"subject"`
+
+	// PredicateType identifies the kind of predicate, such as
+	// "https://slsa.dev/provenance/v1".
+	PredicateType string `json:"predicateType"`
+
+	// Predicate is the raw predicate, to be decoded according to
+	// PredicateType.
+	Predicate json.RawMessage `json:"predicate,omitempty"`
+}
+
+// StatementSubject is an artifact attested by a [Statement].
+type StatementSubject struct {
+	// Name is the artifact name, such as the archive file name.
+	Name string `json:"name"`
+
+	// Digest maps algorithm names (e.g., "sha256") to hex-encoded
+	// digests of the artifact.
+	Digest map[string]string `json:"digest"`
+}
+
+// Verifier checks the signatures of an attestation, for example with
+// Sigstore. It keeps cryptography and trust policy out of this package.
+type Verifier interface {
+	// Verify returns an error unless env is validly signed by a
+	// trusted identity. stmt is the statement decoded from its payload.
+	Verify(ctx context.Context, env *Envelope, stmt *Statement) error
+}
+
+// VerifyAttestation verifies the attestation of a version's source
+// archive, as listed in its attestations.json under the archive file
+// name (see [Source.Filename]).
+//
+// The DSSE envelope is downloaded from the attestation URL and checked
+// against the attestation's integrity, if any. A Sigstore bundle holding
+// the envelope is also accepted. The payload must be an in-toto
+// statement with a subject whose digest matches an integrity of the
+// version's source.json. The envelope and statement are then passed to
+// verifier, which checks the signatures.
+//
+// Returns [ErrNotFound] if the version or its attestations.json does not
+// exist. A missing attestation, an envelope that does not attest the
+// source or an error from verifier is reported as an [*AttestationError].
+func (c *Client) VerifyAttestation(ctx context.Context, module, version string, verifier Verifier) error {
+	if verifier == nil {
+		return errors.New("bcr: VerifyAttestation needs a Verifier")
+	}
+	src, err := c.Source(ctx, module, version)
+	if err != nil {
+		return err
+	}
+	att, err := c.Attestations(ctx, module, version)
+	if err != nil {
+		return err
+	}
+	invalid := func(err error) error {
+		return &AttestationError{Module: module, Version: version, Err: err}
+	}
+
+	name := src.Filename()
+	if name == "" {
+		return invalid(fmt.Errorf("%s source has no archive to attest", src.SourceType()))
+	}
+	entry, ok := att.Attestations[name]
+	if !ok || entry.URL == "" {
+		return invalid(fmt.Errorf("no attestation for %s", name))
+	}
+
+	env, err := c.fetchEnvelope(ctx, ModuleRef{Name: module, Version: version}, entry)
+	if err != nil {
+		return err
+	}
+	stmt, err := decodeStatement(env)
+	if err != nil {
+		return invalid(err)
+	}
+	if err := checkSubject(stmt, sourceIntegrities(src)); err != nil {
+		return invalid(err)
+	}
+	if err := verifier.Verify(ctx, env, stmt); err != nil {
+		return invalid(err)
+	}
+	return nil
+}
+
+// fetchEnvelope downloads the DSSE envelope of an attestation of ref,
+// verifying it against the attestation's integrity if one is given.
+func (c *Client) fetchEnvelope(ctx context.Context, ref ModuleRef, entry Attestation) (*Envelope, error) {
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceDownload)
+	defer cancel()
+
+	var body io.ReadCloser
+	if entry.Integrity != "" {
+		var err error
+		if body, err = c.openArchive(ctx, ref, []string{entry.URL}, []string{entry.Integrity}); err != nil {
+			return nil, err
+		}
+	} else {
+		resp, err := c.getURL(ctx, ref, entry.URL)
+		if err != nil {
+			return nil, err
+		}
+		body = resp.Body
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxEnvelopeSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxEnvelopeSize {
+		return nil, fmt.Errorf("bcr: attestation at %s exceeds %d bytes", entry.URL, maxEnvelopeSize)
+	}
+
+	// Sigstore bundles wrap the envelope; .jsonl files hold one per line.
+	data, _, _ = bytes.Cut(bytes.TrimSpace(data), []byte("\n"))
+	var doc struct {
+		Envelope
+		DSSEEnvelope *Envelope `json:"dsseEnvelope"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("bcr: failed to parse attestation at %s: %w", entry.URL, err)
+	}
+	if doc.DSSEEnvelope != nil {
+		return doc.DSSEEnvelope, nil
+	}
+	return &doc.Envelope, nil
+}
+
+// decodeStatement decodes the in-toto statement carried by env.
+func decodeStatement(env *Envelope) (*Statement, error) {
+	if env.PayloadType != inTotoPayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	// DSSE allows both the standard and URL-safe base64 alphabets.
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		if payload, err = base64.URLEncoding.DecodeString(env.Payload); err != nil {
+			return nil, fmt.Errorf("malformed payload: %w", err)
+		}
+	}
+	var stmt Statement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return nil, fmt.Errorf("malformed statement: %w", err)
+	}
+	return &stmt, nil
+}
+
+// checkSubject reports an error unless a subject of stmt has a digest
+// matching one of integrities.
+func checkSubject(stmt *Statement, integrities []string) error {
+	if len(integrities) == 0 {
+		return errors.New("source has no integrity to match")
+	}
+	for _, integrity := range integrities {
+		algo, digest, err := ParseIntegrity(integrity)
+		if err != nil {
+			return err
+		}
+		for _, subject := range stmt.Subject {
+			if strings.EqualFold(subject.Digest[algo], hex.EncodeToString(digest)) {
+				return nil
+			}
+		}
+	}
+	return errors.New("no subject matches the source integrity")
+}
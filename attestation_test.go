@@ -0,0 +1,164 @@
+package bcr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// verifierFunc adapts a function to the Verifier interface.
+type verifierFunc func(ctx context.Context, env *Envelope, stmt *Statement) error
+
+func (f verifierFunc) Verify(ctx context.Context, env *Envelope, stmt *Statement) error {
+	return f(ctx, env, stmt)
+}
+
+// envelopeFor returns a DSSE envelope attesting an artifact with the
+// given content.
+func envelopeFor(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	stmt, err := json.Marshal(Statement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       []StatementSubject{{Name: name, Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])}}},
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Predicate:     json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := json.Marshal(Envelope{
+		PayloadType: "application/vnd.in-toto+json",
+		Payload:     base64.StdEncoding.EncodeToString(stmt),
+		Signatures:  []EnvelopeSignature{{KeyID: "key", Sig: "c2ln"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	archive := []byte("testmod archive")
+	files := map[string][]byte{
+		"/testmod.intoto.jsonl": envelopeFor(t, "testmod-v1.0.0.tar.gz", archive),
+		"/other.intoto.jsonl":   envelopeFor(t, "testmod-v1.0.0.tar.gz", []byte("other archive")),
+	}
+	files["/bundle.sigstore.json"] = []byte(`{"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json", "dsseEnvelope": ` + string(files["/testmod.intoto.jsonl"]) + `}`)
+
+	var srvURL string
+	attestations := func(path, integrity string) []byte {
+		data, _ := json.Marshal(Attestations{Attestations: map[string]Attestation{
+			"testmod-v1.0.0.tar.gz": {URL: srvURL + path, Integrity: integrity},
+		}})
+		return data
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := files[r.URL.Path]; ok {
+			w.Write(data)
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/modules/testmod/"), "/")
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		switch version, file := parts[0], parts[1]; file {
+		case "source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/testmod-v1.0.0.tar.gz", Integrity: sha256Integrity(archive)})
+		case "attestations.json":
+			switch version {
+			case "1.0.0":
+				w.Write(attestations("/testmod.intoto.jsonl", sha256Integrity(files["/testmod.intoto.jsonl"])))
+			case "1.0.1":
+				w.Write(attestations("/bundle.sigstore.json", ""))
+			case "1.0.2":
+				w.Write(attestations("/other.intoto.jsonl", ""))
+			case "1.0.3":
+				w.Write(attestations("/testmod.intoto.jsonl", sha256Integrity([]byte("tampered"))))
+			case "1.0.4":
+				w.Write([]byte(`{"attestations": {}}`))
+			default:
+				http.NotFound(w, r)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+	accept := verifierFunc(func(context.Context, *Envelope, *Statement) error { return nil })
+
+	t.Run("valid", func(t *testing.T) {
+		var got *Statement
+		verifier := verifierFunc(func(_ context.Context, env *Envelope, stmt *Statement) error {
+			if len(env.Signatures) != 1 || env.Signatures[0].KeyID != "key" {
+				t.Errorf("Signatures = %+v", env.Signatures)
+			}
+			got = stmt
+			return nil
+		})
+		if err := c.VerifyAttestation(ctx, "testmod", "1.0.0", verifier); err != nil {
+			t.Fatalf("VerifyAttestation() error = %v", err)
+		}
+		if got == nil || got.PredicateType != "https://slsa.dev/provenance/v1" || got.Subject[0].Name != "testmod-v1.0.0.tar.gz" {
+			t.Errorf("verifier got statement %+v", got)
+		}
+	})
+
+	t.Run("sigstore bundle", func(t *testing.T) {
+		if err := c.VerifyAttestation(ctx, "testmod", "1.0.1", accept); err != nil {
+			t.Errorf("VerifyAttestation() error = %v", err)
+		}
+	})
+
+	t.Run("rejected by verifier", func(t *testing.T) {
+		errUntrusted := errors.New("untrusted signer")
+		err := c.VerifyAttestation(ctx, "testmod", "1.0.0", verifierFunc(func(context.Context, *Envelope, *Statement) error {
+			return errUntrusted
+		}))
+		var attErr *AttestationError
+		if !errors.As(err, &attErr) || attErr.Version != "1.0.0" || !errors.Is(err, errUntrusted) {
+			t.Errorf("error = %v, want *AttestationError wrapping the verifier error", err)
+		}
+	})
+
+	t.Run("subject mismatch", func(t *testing.T) {
+		called := false
+		err := c.VerifyAttestation(ctx, "testmod", "1.0.2", verifierFunc(func(context.Context, *Envelope, *Statement) error {
+			called = true
+			return nil
+		}))
+		if !errors.Is(err, ErrAttestationInvalid) {
+			t.Errorf("error = %v, want ErrAttestationInvalid", err)
+		}
+		if called {
+			t.Error("verifier called for an envelope not attesting the source")
+		}
+	})
+
+	t.Run("tampered envelope", func(t *testing.T) {
+		if err := c.VerifyAttestation(ctx, "testmod", "1.0.3", accept); !errors.Is(err, ErrIntegrityMismatch) {
+			t.Errorf("error = %v, want ErrIntegrityMismatch", err)
+		}
+	})
+
+	t.Run("no attestation", func(t *testing.T) {
+		if err := c.VerifyAttestation(ctx, "testmod", "1.0.4", accept); !errors.Is(err, ErrAttestationInvalid) {
+			t.Errorf("error = %v, want ErrAttestationInvalid", err)
+		}
+		if err := c.VerifyAttestation(ctx, "testmod", "0.9.0", accept); !errors.Is(err, ErrNotFound) {
+			t.Errorf("missing attestations.json: error = %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,405 @@
+package bcr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores registry responses for a [Client]. See [WithCache].
+//
+// Keys are slash-separated registry paths such as
+// "modules/rules_go/metadata.json". Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the data stored under key and reports whether it was
+	// found. If maxAge is positive, entries stored longer ago than maxAge
+	// are treated as missing; a zero maxAge accepts entries of any age.
+	Get(key string, maxAge time.Duration) ([]byte, bool)
+
+	// Set stores data under key, replacing any existing entry.
+	Set(key string, data []byte)
+
+	// Delete removes the entry for key, if any.
+	Delete(key string)
+}
+
+// cacheClearer is implemented by caches that can remove all entries at
+// once. See [Client.InvalidateAll].
+type cacheClearer interface {
+	Clear() error
+}
+
+// defaultCacheTTL is the maximum age of cached metadata unless
+// [WithCacheTTL] is set.
+const defaultCacheTTL = time.Hour
+
+// MemoryCache is an in-memory [Cache], suitable for short-lived
+// processes and tests. The zero value is not usable; create one with
+// [NewMemoryCache].
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// memoryEntry is a cached value with the time it was stored.
+type memoryEntry struct {
+	data   []byte
+	stored time.Time
+}
+
+// NewMemoryCache creates an empty in-memory cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+// Get implements [Cache].
+func (c *MemoryCache) Get(key string, maxAge time.Duration) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if maxAge > 0 && time.Since(e.stored) > maxAge {
+		return nil, false
+	}
+	return bytes.Clone(e.data), true
+}
+
+// Set implements [Cache].
+func (c *MemoryCache) Set(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = memoryEntry{data: bytes.Clone(data), stored: time.Now()}
+}
+
+// Delete implements [Cache].
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Clear removes all entries. It implements the optional clearing used by
+// [Client.InvalidateAll].
+func (c *MemoryCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	return nil
+}
+
+// fileCache is a [Cache] storing each entry as a file under a directory,
+// at the entry's key path. Entry age is the file's modification time.
+//
+// With compress set, entries are written gzip-compressed at the key path
+// plus [gzipSuffix]. Uncompressed entries are still read, so a directory
+// populated before compression was enabled stays usable.
+//
+// With maxBytes set, the files' total size is kept at or below it by
+// removing least recently used entries after each Set. Recency is
+// tracked in memory; entries already on disk are ordered by modification
+// time when the directory is first scanned.
+type fileCache struct {
+	dir      string
+	compress bool
+	maxBytes int64
+	mu       sync.RWMutex
+
+	lruMu sync.Mutex
+	lru   *lruIndex // nil until the directory is scanned
+}
+
+// gzipSuffix is appended to the path of compressed cache entries.
+const gzipSuffix = ".gz"
+
+func newFileCache(dir string) *fileCache {
+	return &fileCache{dir: dir}
+}
+
+func (c *fileCache) path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key))
+}
+
+func (c *fileCache) Get(key string, maxAge time.Duration) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p := c.path(key)
+	compressed := false
+	if c.compress {
+		if _, err := os.Stat(p + gzipSuffix); err == nil {
+			p, compressed = p+gzipSuffix, true
+		}
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, false
+	}
+	c.touch(p, info.Size())
+
+	if maxAge > 0 {
+		// A modification time in the future means the clock moved
+		// backward since the entry was written; its age is unknown,
+		// so treat it as stale rather than serving it indefinitely.
+		age := time.Since(info.ModTime())
+		if age < 0 || age > maxAge {
+			return nil, false
+		}
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	if compressed {
+		if data, err = gunzip(data); err != nil {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+func (c *fileCache) Set(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return // ignore cache write errors
+	}
+	if !c.compress {
+		if writeFileAtomic(p, data, 0o644) == nil {
+			c.touch(p, int64(len(data)))
+			c.evict(p)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return
+	}
+	if err := zw.Close(); err != nil {
+		return
+	}
+	if writeFileAtomic(p+gzipSuffix, buf.Bytes(), 0o644) == nil {
+		// Drop any uncompressed copy written before compression was
+		// enabled, so the two can't diverge.
+		_ = os.Remove(p)
+		c.forget(p)
+		c.touch(p+gzipSuffix, int64(buf.Len()))
+		c.evict(p + gzipSuffix)
+	}
+}
+
+func (c *fileCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.path(key)
+	_ = os.Remove(p)
+	_ = os.Remove(p + gzipSuffix)
+	c.forget(p)
+	c.forget(p + gzipSuffix)
+}
+
+// gunzip decompresses gzip data.
+func gunzip(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// Clear removes everything in the cache directory, keeping the
+// directory itself.
+func (c *fileCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lruMu.Lock()
+	c.lru = nil // rescanned on next use
+	c.lruMu.Unlock()
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, e.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lruIndex orders the files of a size-limited [fileCache] by last use.
+type lruIndex struct {
+	order *list.List               // of *lruEntry, least recently used first
+	files map[string]*list.Element // by file path
+	size  int64                    // total size of the files
+}
+
+// lruEntry is a cache file and its size.
+type lruEntry struct {
+	path string
+	size int64
+}
+
+// index returns the cache's LRU index, scanning the directory to build
+// it on first use. c.lruMu must be held.
+func (c *fileCache) index() *lruIndex {
+	if c.lru != nil {
+		return c.lru
+	}
+	type file struct {
+		lruEntry
+		modTime time.Time
+	}
+	var found []file
+	_ = filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		// Skip unreadable parts and in-progress writes from writeReaderAtomic.
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			found = append(found, file{lruEntry{p, info.Size()}, info.ModTime()})
+		}
+		return nil
+	})
+	slices.SortFunc(found, func(a, b file) int { return a.modTime.Compare(b.modTime) })
+
+	c.lru = &lruIndex{order: list.New(), files: make(map[string]*list.Element, len(found))}
+	for _, f := range found {
+		c.lru.files[f.path] = c.lru.order.PushBack(&f.lruEntry)
+		c.lru.size += f.size
+	}
+	return c.lru
+}
+
+// touch records a use of the file at p, of the given size.
+func (c *fileCache) touch(p string, size int64) {
+	if c.maxBytes <= 0 {
+		return
+	}
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+
+	idx := c.index()
+	if el, ok := idx.files[p]; ok {
+		e := el.Value.(*lruEntry)
+		idx.size += size - e.size
+		e.size = size
+		idx.order.MoveToBack(el)
+		return
+	}
+	idx.files[p] = idx.order.PushBack(&lruEntry{p, size})
+	idx.size += size
+}
+
+// forget removes the file at p from the LRU index.
+func (c *fileCache) forget(p string) {
+	if c.maxBytes <= 0 {
+		return
+	}
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+
+	idx := c.index()
+	if el, ok := idx.files[p]; ok {
+		idx.size -= el.Value.(*lruEntry).size
+		idx.order.Remove(el)
+		delete(idx.files, p)
+	}
+}
+
+// evict removes least recently used files until the cache fits within
+// maxBytes. The file at keep, just written, is never removed, so an
+// entry larger than the limit is still cached until the next Set.
+// c.mu must be held for writing.
+func (c *fileCache) evict(keep string) {
+	if c.maxBytes <= 0 {
+		return
+	}
+	c.lruMu.Lock()
+	defer c.lruMu.Unlock()
+
+	idx := c.index()
+	for idx.size > c.maxBytes {
+		el := idx.order.Front()
+		e := el.Value.(*lruEntry)
+		if e.path == keep {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			break // leave it indexed; a later Set tries again
+		}
+		idx.size -= e.size
+		idx.order.Remove(el)
+		delete(idx.files, e.path)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory
+// as name and renames it into place, so readers never observe a partially
+// written file. The data is synced before the rename so a crash leaves
+// either the old contents or the new ones.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	return writeReaderAtomic(filepath.Dir(name), name, bytes.NewReader(data), perm)
+}
+
+// writeReaderAtomic is like [writeFileAtomic] but streams the content
+// from r into a temporary file in tmpDir, which must be on the same
+// filesystem as name. If reading r fails, name is left untouched. The
+// directory of name is created only once r has been read, so a failed
+// write leaves no new directory behind.
+func writeReaderAtomic(tmpDir, name string, r io.Reader, perm os.FileMode) error {
+	f, err := os.CreateTemp(tmpDir, "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil && filepath.Dir(name) != tmpDir {
+		err = os.MkdirAll(filepath.Dir(name), 0o755)
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,474 @@
+package bcr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache()
+	key := "modules/testmod/metadata.json"
+
+	if _, ok := c.Get(key, 0); ok {
+		t.Fatal("Get() on empty cache ok = true")
+	}
+
+	data := []byte(`{"versions":["1.0.0"]}`)
+	c.Set(key, data)
+	data[0] = 'X' // the cache must hold its own copy
+
+	got, ok := c.Get(key, time.Hour)
+	if !ok || string(got) != `{"versions":["1.0.0"]}` {
+		t.Errorf("Get() = %q, %v", got, ok)
+	}
+
+	t.Run("max age", func(t *testing.T) {
+		c.mu.Lock()
+		e := c.entries[key]
+		e.stored = time.Now().Add(-2 * time.Hour)
+		c.entries[key] = e
+		c.mu.Unlock()
+
+		if _, ok := c.Get(key, time.Hour); ok {
+			t.Error("Get() returned an entry older than maxAge")
+		}
+		if _, ok := c.Get(key, 0); !ok {
+			t.Error("Get() with zero maxAge should ignore age")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		c.Delete(key)
+		if _, ok := c.Get(key, 0); ok {
+			t.Error("Get() after Delete() ok = true")
+		}
+	})
+}
+
+func TestWithMemoryCache(t *testing.T) {
+	requestCount := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		switch r.URL.Path {
+		case "/modules/cached/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/modules/cached/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+	c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()), WithCacheDir(cacheDir))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.Metadata(ctx, "cached"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if _, err := c.Source(ctx, "cached", "1.0.0"); err != nil {
+			t.Fatalf("Source() error = %v", err)
+		}
+	}
+	if requestCount != 2 {
+		t.Errorf("requestCount = %d, want 2 (should use cache)", requestCount)
+	}
+
+	// WithCache takes precedence, so nothing is written to the directory.
+	if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+		t.Errorf("cache directory should be unused, contains %d entries", len(entries))
+	}
+}
+
+func TestCacheAtomicWrites(t *testing.T) {
+	c := newFileCache(t.TempDir())
+	key := "modules/atomic/metadata.json"
+	small := []byte(`{"versions":["1.0.0"]}`)
+	large := make([]byte, 1<<20)
+	for i := range large {
+		large[i] = 'x'
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if (i+j)%2 == 0 {
+					c.Set(key, small)
+				} else {
+					c.Set(key, large)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 100; i++ {
+		if data, ok := c.Get(key, 0); ok && len(data) != len(small) && len(data) != len(large) {
+			t.Fatalf("torn read: got %d bytes", len(data))
+		}
+	}
+	wg.Wait()
+
+	entries, err := os.ReadDir(filepath.Join(c.dir, "modules", "atomic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("cache directory contains %v, want only metadata.json", names)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/modules/a/metadata.json", "/modules/b/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/modules/a/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	fetchAll := func(t *testing.T, c *Client) {
+		t.Helper()
+		for _, mod := range []string{"a", "b"} {
+			if _, err := c.Metadata(ctx, mod); err != nil {
+				t.Fatalf("Metadata(%q) error = %v", mod, err)
+			}
+		}
+		if _, err := c.Source(ctx, "a", "1.0.0"); err != nil {
+			t.Fatalf("Source() error = %v", err)
+		}
+	}
+	reset := func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		got := requests
+		requests = map[string]int{}
+		return got
+	}
+
+	caches := map[string]Option{
+		"dir":    WithCacheDir(t.TempDir()),
+		"memory": WithCache(NewMemoryCache()),
+	}
+	for name, opt := range caches {
+		t.Run(name, func(t *testing.T) {
+			c := New(WithBaseURL(srv.URL), opt)
+			fetchAll(t, c)
+			reset()
+
+			if err := c.InvalidateModule("a"); err != nil {
+				t.Fatalf("InvalidateModule() error = %v", err)
+			}
+			fetchAll(t, c)
+			got := reset()
+			if got["/modules/a/metadata.json"] != 1 || len(got) != 1 {
+				t.Errorf("requests after InvalidateModule = %v, want only a's metadata", got)
+			}
+
+			if err := c.InvalidateAll(); err != nil {
+				t.Fatalf("InvalidateAll() error = %v", err)
+			}
+			fetchAll(t, c)
+			if got := reset(); len(got) != 3 {
+				t.Errorf("requests after InvalidateAll = %v, want all 3 refetched", got)
+			}
+		})
+	}
+
+	t.Run("caching disabled", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL))
+		if err := c.InvalidateModule("a"); err != nil {
+			t.Errorf("InvalidateModule() error = %v", err)
+		}
+		if err := c.InvalidateAll(); err != nil {
+			t.Errorf("InvalidateAll() error = %v", err)
+		}
+	})
+
+	t.Run("cache without Clear", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithCache(getSetCache{NewMemoryCache()}))
+		if err := c.InvalidateAll(); err == nil {
+			t.Error("expected error for cache without Clear")
+		}
+	})
+
+	t.Run("missing cache directory", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithCacheDir(filepath.Join(t.TempDir(), "absent")))
+		if err := c.InvalidateAll(); err != nil {
+			t.Errorf("InvalidateAll() error = %v", err)
+		}
+	})
+}
+
+// getSetCache hides the optional methods of the wrapped cache.
+type getSetCache struct{ c Cache }
+
+func (g getSetCache) Get(key string, maxAge time.Duration) ([]byte, bool) {
+	return g.c.Get(key, maxAge)
+}
+func (g getSetCache) Set(key string, data []byte) { g.c.Set(key, data) }
+func (g getSetCache) Delete(key string)           { g.c.Delete(key) }
+
+func TestCacheRevalidation(t *testing.T) {
+	const etag = `"v1"`
+	lastModified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Format(http.TimeFormat)
+
+	var mu sync.Mutex
+	var full, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/modules/etag/metadata.json":
+			if r.Header.Get("If-None-Match") == etag {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		case "/modules/lastmod/metadata.json":
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", lastModified)
+		case "/modules/plain/metadata.json":
+			if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+				t.Errorf("conditional request sent without stored validators")
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		full++
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	counts := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		f, n := full, notModified
+		full, notModified = 0, 0
+		return f, n
+	}
+
+	for _, module := range []string{"etag", "lastmod"} {
+		t.Run(module, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			// Every entry is immediately stale, forcing revalidation.
+			c := New(WithBaseURL(srv.URL), WithCacheDir(cacheDir), WithCacheTTL(time.Nanosecond))
+			cachePath := filepath.Join(cacheDir, "modules", module, "metadata.json")
+
+			if _, err := c.Metadata(ctx, module); err != nil {
+				t.Fatalf("Metadata() error = %v", err)
+			}
+			past := time.Now().Add(-time.Hour)
+			if err := os.Chtimes(cachePath, past, past); err != nil {
+				t.Fatal(err)
+			}
+
+			for range 2 {
+				meta, err := c.Metadata(ctx, module)
+				if err != nil {
+					t.Fatalf("Metadata() error = %v", err)
+				}
+				if meta.Latest() != "1.0.0" {
+					t.Errorf("Latest() = %q, want %q", meta.Latest(), "1.0.0")
+				}
+			}
+			if f, n := counts(); f != 1 || n != 2 {
+				t.Errorf("full responses = %d, 304s = %d, want 1 and 2", f, n)
+			}
+
+			info, err := os.Stat(cachePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().After(past) {
+				t.Error("304 response should refresh the cache entry's mtime")
+			}
+		})
+	}
+
+	t.Run("no validators", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()), WithCacheTTL(time.Nanosecond))
+		for range 2 {
+			if _, err := c.Metadata(ctx, "plain"); err != nil {
+				t.Fatalf("Metadata() error = %v", err)
+			}
+		}
+		if f, n := counts(); f != 2 || n != 0 {
+			t.Errorf("full responses = %d, 304s = %d, want 2 and 0", f, n)
+		}
+	})
+
+	t.Run("unconditional 304 is an error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		}))
+		defer srv.Close()
+
+		c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()))
+		if _, err := c.Metadata(ctx, "testmod"); err == nil {
+			t.Error("expected error for 304 without a conditional request")
+		}
+	})
+}
+
+func TestCacheCompression(t *testing.T) {
+	dir := t.TempDir()
+	c := newFileCache(dir)
+	c.compress = true
+	key := "modules/big/metadata.json"
+	data := []byte(`{"versions":[` + strings.Repeat(`"1.0.0",`, 1000) + `"2.0.0"]}`)
+
+	c.Set(key, data)
+	got, ok := c.Get(key, time.Hour)
+	if !ok || !bytes.Equal(got, data) {
+		t.Fatalf("Get() = %d bytes, %v; want the %d bytes stored", len(got), ok, len(data))
+	}
+
+	p := filepath.Join(dir, "modules", "big", "metadata.json")
+	info, err := os.Stat(p + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= int64(len(data)) {
+		t.Errorf("compressed file is %d bytes, want fewer than %d", info.Size(), len(data))
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("uncompressed file exists alongside compressed one: %v", err)
+	}
+
+	c.Delete(key)
+	if _, ok := c.Get(key, 0); ok {
+		t.Error("Get() after Delete ok = true")
+	}
+}
+
+func TestCacheCompressionFallback(t *testing.T) {
+	dir := t.TempDir()
+	key := "modules/old/metadata.json"
+	data := []byte(`{"versions":["1.0.0"]}`)
+	newFileCache(dir).Set(key, data)
+
+	// An entry written before compression was enabled is still served.
+	c := newFileCache(dir)
+	c.compress = true
+	if got, ok := c.Get(key, 0); !ok || !bytes.Equal(got, data) {
+		t.Fatalf("Get() = %q, %v; want %q", got, ok, data)
+	}
+
+	// Rewriting it replaces the uncompressed copy.
+	c.Set(key, data)
+	entries, err := os.ReadDir(filepath.Join(dir, "modules", "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "metadata.json.gz" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("cache directory contains %v, want only metadata.json.gz", names)
+	}
+}
+
+func TestWithCacheCompression(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"versions":["1.0.0"]}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	client := New(WithBaseURL(srv.URL), WithCacheDir(dir), WithCacheCompression(true))
+	if _, err := client.Metadata(context.Background(), "gz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "modules", "gz", "metadata.json.gz")); err != nil {
+		t.Errorf("compressed metadata not written: %v", err)
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	entry := bytes.Repeat([]byte("x"), 100)
+
+	// Entries already on disk are ordered by modification time.
+	seed := newFileCache(dir)
+	seed.Set("modules/a/metadata.json", entry)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "modules", "a", "metadata.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	seed.Set("modules/b/metadata.json", entry)
+
+	c := newFileCache(dir)
+	c.maxBytes = 250
+	c.Set("modules/c/1.0.0/source.json", entry)
+	if _, ok := c.Get("modules/a/metadata.json", 0); ok {
+		t.Error("entry with the oldest modification time was not evicted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "modules", "a", "metadata.json")); !os.IsNotExist(err) {
+		t.Errorf("evicted file still on disk: %v", err)
+	}
+
+	// Reading b makes c the least recently used entry.
+	if _, ok := c.Get("modules/b/metadata.json", 0); !ok {
+		t.Fatal("Get(b) ok = false")
+	}
+	c.Set("modules/d/1.0.0/MODULE.bazel", entry)
+	if _, ok := c.Get("modules/c/1.0.0/source.json", 0); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, key := range []string{"modules/b/metadata.json", "modules/d/1.0.0/MODULE.bazel"} {
+		if _, ok := c.Get(key, 0); !ok {
+			t.Errorf("Get(%q) ok = false, want recently used entry kept", key)
+		}
+	}
+}
+
+func TestCacheMaxBytesOversized(t *testing.T) {
+	c := newFileCache(t.TempDir())
+	c.maxBytes = 10
+	c.Set("modules/small/metadata.json", []byte("tiny"))
+	big := bytes.Repeat([]byte("x"), 100)
+	c.Set("modules/big/metadata.json", big)
+
+	// The entry just written is kept even though it alone exceeds the limit.
+	if got, ok := c.Get("modules/big/metadata.json", 0); !ok || !bytes.Equal(got, big) {
+		t.Error("oversized entry was not kept")
+	}
+	if _, ok := c.Get("modules/small/metadata.json", 0); ok {
+		t.Error("older entry was not evicted")
+	}
+}
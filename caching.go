@@ -0,0 +1,172 @@
+package bcr
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// CachingRegistry is a Registry that caches the results of another, such
+// as a [FileRegistry] on a network filesystem or a [CompositeRegistry].
+//
+// Metadata and the module list are cached for the configured TTL; source
+// information and MODULE.bazel files are immutable and cached
+// indefinitely. Errors are not cached. Entries use the same keys as
+// [Client] (e.g., "modules/rules_go/metadata.json"), so a [Cache] should
+// not be shared between a CachingRegistry and a Client for different
+// registries.
+type CachingRegistry struct {
+	inner Registry
+	cache Cache
+	ttl   time.Duration
+}
+
+// NewCachingRegistry creates a registry serving inner's results from
+// cache. Metadata older than ttl is fetched again; a zero ttl keeps it
+// until it is removed from the cache.
+func NewCachingRegistry(inner Registry, cache Cache, ttl time.Duration) *CachingRegistry {
+	return &CachingRegistry{inner: inner, cache: cache, ttl: ttl}
+}
+
+// Metadata fetches module metadata from the cache or the inner registry.
+func (r *CachingRegistry) Metadata(ctx context.Context, module string) (*Metadata, error) {
+	key := DefaultPathMapper(module, "", "metadata.json")
+	if data, ok := r.cache.Get(key, r.ttl); ok {
+		var meta Metadata
+		if err := json.Unmarshal(data, &meta); err == nil {
+			return &meta, nil
+		}
+		r.cache.Delete(key)
+	}
+
+	meta, err := r.inner.Metadata(ctx, module)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := marshalMetadata(meta); err == nil {
+		r.cache.Set(key, data)
+	}
+	return meta, nil
+}
+
+// Source fetches source information from the cache or the inner registry.
+func (r *CachingRegistry) Source(ctx context.Context, module, version string) (*Source, error) {
+	key := DefaultPathMapper(module, version, "source.json")
+	if data, ok := r.cache.Get(key, 0); ok {
+		var src Source
+		if err := json.Unmarshal(data, &src); err == nil {
+			return &src, nil
+		}
+		r.cache.Delete(key)
+	}
+
+	src, err := r.inner.Source(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := marshalSource(src); err == nil {
+		r.cache.Set(key, data)
+	}
+	return src, nil
+}
+
+// ModuleFile fetches the MODULE.bazel content from the cache or the inner
+// registry.
+func (r *CachingRegistry) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	key := DefaultPathMapper(module, version, "MODULE.bazel")
+	if data, ok := r.cache.Get(key, 0); ok {
+		return data, nil
+	}
+
+	data, err := r.inner.ModuleFile(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Set(key, data)
+	return data, nil
+}
+
+// ListModules returns the inner registry's module names, cached like
+// metadata. It returns [ErrListingNotSupported] if the inner registry does
+// not implement [ModuleLister].
+func (r *CachingRegistry) ListModules(ctx context.Context) ([]string, error) {
+	lister, ok := r.inner.(ModuleLister)
+	if !ok {
+		return nil, ErrListingNotSupported
+	}
+
+	key := DefaultPathMapper("", "", "index.json")
+	if data, ok := r.cache.Get(key, r.ttl); ok {
+		var modules []string
+		if err := json.Unmarshal(data, &modules); err == nil {
+			return modules, nil
+		}
+		r.cache.Delete(key)
+	}
+
+	modules, err := lister.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(modules); err == nil {
+		r.cache.Set(key, data)
+	}
+	return modules, nil
+}
+
+// String returns the cached registry, e.g. "cached(file:///srv/bcr)".
+func (r *CachingRegistry) String() string {
+	if s, ok := r.inner.(interface{ String() string }); ok {
+		return "cached(" + s.String() + ")"
+	}
+	return "cached(?)"
+}
+
+// Type returns the registry type ("caching").
+func (r *CachingRegistry) Type() string {
+	return "caching"
+}
+
+// marshalMetadata encodes meta as metadata.json, keeping the dates of
+// yanked versions decoded from the object form.
+func marshalMetadata(meta *Metadata) ([]byte, error) {
+	type metadataAlias Metadata
+	aux := struct {
+		*metadataAlias
+		YankedVersions map[string]any `json:"yanked_versions,omitempty"`
+	}{metadataAlias: (*metadataAlias)(meta)}
+	if meta.YankedVersions != nil {
+		aux.YankedVersions = make(map[string]any, len(meta.YankedVersions))
+		for v, reason := range meta.YankedVersions {
+			if info, ok := meta.yankDetails[v]; ok {
+				aux.YankedVersions[v] = info
+			} else {
+				aux.YankedVersions[v] = reason
+			}
+		}
+	}
+	return json.Marshal(aux)
+}
+
+// marshalSource encodes src as source.json, writing every entry of
+// Integrities rather than only the first.
+func marshalSource(src *Source) ([]byte, error) {
+	type sourceAlias Source
+	aux := struct {
+		*sourceAlias
+		Integrity any `json:"integrity,omitempty"`
+	}{sourceAlias: (*sourceAlias)(src)}
+	switch {
+	case len(src.Integrities) > 1:
+		aux.Integrity = src.Integrities
+	case src.Integrity != "":
+		aux.Integrity = src.Integrity
+	}
+	return json.Marshal(aux)
+}
+
+// Ensure CachingRegistry implements Registry at compile time.
+var _ Registry = (*CachingRegistry)(nil)
+
+// Ensure CachingRegistry implements ModuleLister at compile time.
+var _ ModuleLister = (*CachingRegistry)(nil)
@@ -0,0 +1,148 @@
+package bcr
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// countingRegistry records how often each method of the wrapped
+// registry is called.
+type countingRegistry struct {
+	*FSRegistry
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *countingRegistry) count(method string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[method]++
+}
+
+func (r *countingRegistry) Metadata(ctx context.Context, module string) (*Metadata, error) {
+	r.count("Metadata")
+	return r.FSRegistry.Metadata(ctx, module)
+}
+
+func (r *countingRegistry) Source(ctx context.Context, module, version string) (*Source, error) {
+	r.count("Source")
+	return r.FSRegistry.Source(ctx, module, version)
+}
+
+func (r *countingRegistry) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	r.count("ModuleFile")
+	return r.FSRegistry.ModuleFile(ctx, module, version)
+}
+
+func (r *countingRegistry) ListModules(ctx context.Context) ([]string, error) {
+	r.count("ListModules")
+	return r.FSRegistry.ListModules(ctx)
+}
+
+func newCountingRegistry() *countingRegistry {
+	fsys := fstest.MapFS{
+		"modules/zlib/metadata.json": {Data: []byte(`{
+			"versions": ["1.2.13", "1.3.1"],
+			"yanked_versions": {"1.2.13": {"reason": "CVE", "date": "2024-01-02"}}
+		}`)},
+		"modules/zlib/1.3.1/source.json": {Data: []byte(`{
+			"url": "https://example.com/zlib.tar.gz",
+			"integrity": ["sha256-AAAA", "sha512-BBBB"]
+		}`)},
+		"modules/zlib/1.3.1/MODULE.bazel": {Data: []byte(`module(name = "zlib", version = "1.3.1")`)},
+	}
+	return &countingRegistry{FSRegistry: NewFSRegistry(fsys), calls: make(map[string]int)}
+}
+
+func TestCachingRegistry(t *testing.T) {
+	inner := newCountingRegistry()
+	reg := NewCachingRegistry(inner, NewMemoryCache(), time.Hour)
+	ctx := context.Background()
+
+	for range 3 {
+		meta, err := reg.Metadata(ctx, "zlib")
+		if err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if info, ok := meta.YankReasonDetailed("1.2.13"); !ok || info.Date != "2024-01-02" {
+			t.Errorf("YankReasonDetailed() = %+v, %v; want the cached date", info, ok)
+		}
+
+		src, err := reg.Source(ctx, "zlib", "1.3.1")
+		if err != nil {
+			t.Fatalf("Source() error = %v", err)
+		}
+		if want := []string{"sha256-AAAA", "sha512-BBBB"}; !slices.Equal(src.Integrities, want) {
+			t.Errorf("Integrities = %v, want %v", src.Integrities, want)
+		}
+
+		if _, err := reg.ModuleFile(ctx, "zlib", "1.3.1"); err != nil {
+			t.Fatalf("ModuleFile() error = %v", err)
+		}
+		if modules, err := reg.ListModules(ctx); err != nil || !slices.Equal(modules, []string{"zlib"}) {
+			t.Fatalf("ListModules() = %v, %v", modules, err)
+		}
+	}
+
+	for _, method := range []string{"Metadata", "Source", "ModuleFile", "ListModules"} {
+		if n := inner.calls[method]; n != 1 {
+			t.Errorf("inner %s called %d times, want 1", method, n)
+		}
+	}
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		for range 2 {
+			if _, err := reg.Metadata(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("error = %v, want ErrNotFound", err)
+			}
+		}
+		if n := inner.calls["Metadata"]; n != 3 {
+			t.Errorf("inner Metadata called %d times, want 3", n)
+		}
+	})
+}
+
+func TestCachingRegistryTTL(t *testing.T) {
+	inner := newCountingRegistry()
+	reg := NewCachingRegistry(inner, NewMemoryCache(), 20*time.Millisecond)
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := reg.Metadata(ctx, "zlib"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := reg.Source(ctx, "zlib", "1.3.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := reg.Metadata(ctx, "zlib"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Source(ctx, "zlib", "1.3.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := inner.calls["Metadata"]; n != 2 {
+		t.Errorf("inner Metadata called %d times, want 2 (once per TTL)", n)
+	}
+	if n := inner.calls["Source"]; n != 1 {
+		t.Errorf("inner Source called %d times, want 1 (immutable)", n)
+	}
+}
+
+func TestCachingRegistryListingNotSupported(t *testing.T) {
+	inner := NewCompositeRegistry(newCountingRegistry())
+	reg := NewCachingRegistry(inner, NewMemoryCache(), time.Hour)
+	if _, err := reg.ListModules(context.Background()); !errors.Is(err, ErrListingNotSupported) {
+		t.Errorf("error = %v, want ErrListingNotSupported", err)
+	}
+	if got := reg.String(); got != "cached(composite(fs))" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -1,16 +1,24 @@
 package bcr
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
+	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
-	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -23,10 +31,66 @@ const DefaultBaseURL = "https://bcr.bazel.build"
 // Client is safe for concurrent use. All methods that perform I/O
 // accept a context for cancellation and timeout control.
 type Client struct {
-	baseURL   string
+	baseURL   *url.URL
 	http      *http.Client
 	userAgent string
-	cache     *cache
+	cache     Cache
+	cacheTTL  time.Duration
+
+	// archiveHTTP sends requests for source archives. It is http without
+	// the redirect override of WithNoRedirects, since archive hosts
+	// routinely redirect.
+	archiveHTTP *http.Client
+
+	// baseURLErr is why the configured base URL is invalid, returned by
+	// every request. baseURL is nil if it is set.
+	baseURLErr error
+
+	// authorization is the Authorization header value sent to the
+	// registry. It must never be logged.
+	authorization string
+
+	sourceFile string
+	moduleFile string
+	pathMapper func(module, version, file string) string
+
+	latestFallback    bool
+	validateIntegrity bool
+	sortVersions      bool
+
+	timeout    time.Duration
+	opTimeouts map[ResourceKind]time.Duration
+	query      url.Values
+
+	retryAttempts int
+	retryDelay    time.Duration
+	limiter       *rateLimiter
+
+	maxConcurrency int
+	logger         *slog.Logger
+	metricsHook    func(MetricEvent)
+
+	headVersionExists bool
+
+	// allowedSourceTypes is the set of source types that may be
+	// downloaded, or nil to allow all.
+	allowedSourceTypes map[string]bool
+
+	// allowedHosts lists the hosts sources may be downloaded from, or
+	// is nil to allow all.
+	allowedHosts []string
+
+	// rewriteURL maps each archive URL to the URL actually fetched, or
+	// is nil.
+	rewriteURL func(string) string
+
+	// pageIndex memoizes the module index for ListModulesPage when no
+	// cache is configured, so that paging does not refetch it.
+	pageIndex struct {
+		sync.Mutex
+		modules []string
+		fetched time.Time
+	}
 }
 
 // New creates a new registry client with the given options.
@@ -35,22 +99,84 @@ type Client struct {
 // https://bcr.bazel.build with no caching.
 func New(opts ...Option) *Client {
 	cfg := &clientConfig{
-		baseURL:   DefaultBaseURL,
-		http:      http.DefaultClient,
-		userAgent: "go-bcr/1.0",
+		baseURL:    DefaultBaseURL,
+		http:       http.DefaultClient,
+		userAgent:  "go-bcr/1.0",
+		sourceFile: "source.json",
+		moduleFile: "MODULE.bazel",
+		pathMapper: DefaultPathMapper,
 	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
 
 	c := &Client{
-		baseURL:   cfg.baseURL,
-		http:      cfg.http,
-		userAgent: cfg.userAgent,
+		http:       cfg.http,
+		userAgent:  cfg.userAgent,
+		sourceFile: cfg.sourceFile,
+		moduleFile: cfg.moduleFile,
+		pathMapper: cfg.pathMapper,
+
+		authorization: cfg.authorization,
+
+		latestFallback:    cfg.latestFallback,
+		validateIntegrity: cfg.validateIntegrity,
+		sortVersions:      cfg.sortVersions,
+
+		timeout:    cfg.timeout,
+		opTimeouts: cfg.opTimeouts,
+		query:      cfg.query,
+
+		retryAttempts: cfg.retryAttempts,
+		retryDelay:    cfg.retryDelay,
+
+		maxConcurrency: cfg.maxConcurrency,
+		logger:         cfg.logger,
+		metricsHook:    cfg.metricsHook,
+
+		headVersionExists: cfg.headVersionExists,
+
+		allowedSourceTypes: cfg.allowedSourceTypes,
+		allowedHosts:       cfg.allowedHosts,
+		rewriteURL:         cfg.rewriteURL,
+	}
+	c.baseURL, c.baseURLErr = parseBaseURL(cfg.baseURL)
+	if c.maxConcurrency <= 0 {
+		c.maxConcurrency = defaultMaxConcurrency
+	}
+	if cfg.uaSuffix != "" {
+		c.userAgent += " " + cfg.uaSuffix
+	}
+	if cfg.rateLimit > 0 {
+		c.limiter = newRateLimiter(cfg.rateLimit, cfg.rateBurst)
+	}
+	if cfg.transport != nil {
+		// Copy so the caller's (or the shared default) client is untouched.
+		hc := *cfg.http
+		hc.Transport = cfg.transport
+		c.http = &hc
+	}
+	c.archiveHTTP = c.http
+	if cfg.noRedirects {
+		hc := *c.http
+		hc.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		c.http = &hc
 	}
 
-	if cfg.cacheDir != "" {
-		c.cache = newCache(cfg.cacheDir, cfg.cacheTTL)
+	switch {
+	case cfg.cache != nil:
+		c.cache = cfg.cache
+	case cfg.cacheDir != "":
+		fc := newFileCache(cfg.cacheDir)
+		fc.compress = cfg.cacheCompression
+		fc.maxBytes = cfg.cacheMaxBytes
+		c.cache = fc
+	}
+	c.cacheTTL = cfg.cacheTTL
+	if c.cacheTTL == 0 {
+		c.cacheTTL = defaultCacheTTL
 	}
 
 	return c
@@ -61,14 +187,52 @@ type clientConfig struct {
 	baseURL   string
 	http      *http.Client
 	userAgent string
+	uaSuffix  string
+	cache     Cache
 	cacheDir  string
 	cacheTTL  time.Duration
+
+	cacheCompression bool
+	cacheMaxBytes    int64
+
+	authorization string
+
+	sourceFile string
+	moduleFile string
+	pathMapper func(module, version, file string) string
+
+	latestFallback    bool
+	validateIntegrity bool
+	sortVersions      bool
+
+	timeout     time.Duration
+	opTimeouts  map[ResourceKind]time.Duration
+	query       url.Values
+	noRedirects bool
+	transport   http.RoundTripper
+
+	retryAttempts int
+	retryDelay    time.Duration
+	rateLimit     float64
+	rateBurst     int
+
+	maxConcurrency int
+	logger         *slog.Logger
+	metricsHook    func(MetricEvent)
+
+	headVersionExists bool
+
+	allowedSourceTypes map[string]bool
+	allowedHosts       []string
+	rewriteURL         func(string) string
 }
 
 // Option configures a [Client].
 type Option func(*clientConfig)
 
-// WithBaseURL sets the registry base URL.
+// WithBaseURL sets the registry base URL. It must be an absolute http or
+// https URL; trailing slashes are removed. If it is not valid, every
+// request made by the client returns an error saying why.
 //
 // Default: https://bcr.bazel.build
 func WithBaseURL(baseURL string) Option {
@@ -86,6 +250,22 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
+// WithTransport sets the [http.RoundTripper] that sends requests, such as
+// one adding tracing or authentication. It receives requests after the
+// client has set its own headers, and may add headers of its own.
+//
+// The transport replaces the Transport of the client given to
+// [WithHTTPClient], which otherwise keeps its settings such as Timeout;
+// that client itself is not modified. To wrap the default behavior, wrap
+// [http.DefaultTransport].
+//
+// Default: the Transport of the HTTP client
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *clientConfig) {
+		c.transport = rt
+	}
+}
+
 // WithUserAgent sets the User-Agent header for requests.
 //
 // Default: "go-bcr/1.0"
@@ -95,6 +275,42 @@ func WithUserAgent(ua string) Option {
 	}
 }
 
+// WithUserAgentSuffix appends s to the User-Agent header instead of
+// replacing it, so registry operators can identify both the library and
+// the embedding application (e.g., "go-bcr/1.0 myapp/1.2.3").
+//
+// The suffix is appended to the value set by [WithUserAgent], if any.
+func WithUserAgentSuffix(s string) Option {
+	return func(c *clientConfig) {
+		c.uaSuffix = s
+	}
+}
+
+// WithBearerToken authenticates requests to the registry with token, sent
+// as "Authorization: Bearer <token>". It is not sent when downloading
+// source archives, which are hosted elsewhere, and is never logged.
+//
+// It replaces any credentials set with [WithBasicAuth]. A transport set
+// with [WithTransport] sees the header and may override it.
+//
+// Default: no authentication
+func WithBearerToken(token string) Option {
+	return func(c *clientConfig) {
+		c.authorization = "Bearer " + token
+	}
+}
+
+// WithBasicAuth authenticates requests to the registry with HTTP basic
+// authentication. Like [WithBearerToken], the credentials are sent only to
+// the registry and never logged, and replace any set before.
+//
+// Default: no authentication
+func WithBasicAuth(user, pass string) Option {
+	return func(c *clientConfig) {
+		c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	}
+}
+
 // WithCacheDir enables local caching in the specified directory.
 //
 // The cache stores metadata and source information to reduce
@@ -107,12 +323,62 @@ func WithCacheDir(dir string) Option {
 	}
 }
 
+// WithCacheCompression sets whether the [WithCacheDir] cache stores
+// entries gzip-compressed, in files named after the uncompressed ones
+// with a ".gz" suffix. Large metadata.json files compress well.
+//
+// Entries written uncompressed, for example before compression was
+// enabled, are still read. It has no effect on a [Cache] given to
+// [WithCache].
+//
+// Default: false
+func WithCacheCompression(compress bool) Option {
+	return func(c *clientConfig) {
+		c.cacheCompression = compress
+	}
+}
+
+// WithCacheMaxBytes limits the total size of the [WithCacheDir] cache to
+// n bytes. When a write takes the cache over the limit, the least
+// recently used entries are removed, whatever kind of file they hold.
+// A zero or negative n means no limit.
+//
+// Existing files are counted when the client first uses the directory;
+// files other processes add later are not. It has no effect on a [Cache]
+// given to [WithCache].
+//
+// Default: no limit
+func WithCacheMaxBytes(n int64) Option {
+	return func(c *clientConfig) {
+		c.cacheMaxBytes = n
+	}
+}
+
+// WithCache sets the cache used to store registry responses, such as a
+// [MemoryCache] or a custom implementation backed by a shared store.
+//
+// It takes precedence over [WithCacheDir].
+//
+// Default: no caching
+func WithCache(cache Cache) Option {
+	return func(c *clientConfig) {
+		c.cache = cache
+	}
+}
+
 // WithCacheTTL sets the cache time-to-live duration.
 //
 // Cached entries older than this duration are considered stale
 // and will be refetched. This only applies to metadata; source
 // information is cached indefinitely as it's immutable.
 //
+// If the registry sent an ETag or Last-Modified header with the cached
+// metadata, the refetch is a conditional request, and a 304 Not Modified
+// response renews the cached copy without downloading it again.
+//
+// Without a cache, the TTL still bounds how long [Client.ListModulesPage]
+// reuses the module index it keeps in memory.
+//
 // Default: 1 hour
 func WithCacheTTL(ttl time.Duration) Option {
 	return func(c *clientConfig) {
@@ -120,35 +386,433 @@ func WithCacheTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithSourceFilename sets the name of the per-version source file for
+// registries that deviate from the BCR layout.
+//
+// Default: "source.json"
+func WithSourceFilename(name string) Option {
+	return func(c *clientConfig) {
+		c.sourceFile = name
+	}
+}
+
+// WithModuleFilename sets the name of the per-version module file for
+// registries that deviate from the BCR layout.
+//
+// Default: "MODULE.bazel"
+func WithModuleFilename(name string) Option {
+	return func(c *clientConfig) {
+		c.moduleFile = name
+	}
+}
+
+// WithPathMapper sets how registry file paths are built, for registries
+// that deviate from the BCR layout, for example by prefixing paths with a
+// tenant ID. The mapper returns the file's path relative to the base URL;
+// the path is also its cache key.
+//
+// file is the file name: "metadata.json" (version is empty),
+// "index.json" (module and version are empty), the names set by
+// [WithSourceFilename] and [WithModuleFilename], or another version file
+// such as "presubmit.yml". Mappers can wrap [DefaultPathMapper].
+//
+// Default: [DefaultPathMapper]
+func WithPathMapper(mapper func(module, version, file string) string) Option {
+	return func(c *clientConfig) {
+		if mapper == nil {
+			mapper = DefaultPathMapper
+		}
+		c.pathMapper = mapper
+	}
+}
+
+// DefaultPathMapper returns the path of a registry file in the BCR
+// layout: modules/<module>/metadata.json, modules/<module>/<version>/<file>
+// and modules/index.json. See [WithPathMapper].
+func DefaultPathMapper(module, version, file string) string {
+	return path.Join("modules", module, version, file)
+}
+
+// WithHeadVersionExists makes [Client.VersionExists] send a HEAD request
+// for the version's source.json instead of loading the module's metadata.
+//
+// This avoids downloading and parsing large version lists, but only
+// checks that the version's files are present: a version directory the
+// metadata does not list, for example one being added or removed, is
+// reported as existing. Like the default, it does not consider yanking.
+//
+// Default: VersionExists uses the metadata version list
+func WithHeadVersionExists() Option {
+	return func(c *clientConfig) {
+		c.headVersionExists = true
+	}
+}
+
+// WithLatestFallbackToYanked makes [Client.Latest] return the newest
+// version even if it is yanked, when every version of a module is yanked.
+//
+// Callers can detect this case with [Metadata.IsYanked]. A module with no
+// versions at all still returns [ErrNoVersions].
+//
+// Default: Latest returns [ErrNotFound] when all versions are yanked
+func WithLatestFallbackToYanked() Option {
+	return func(c *clientConfig) {
+		c.latestFallback = true
+	}
+}
+
+// WithSortedVersions makes [Client.Latest], [Client.LatestStable] and
+// [Client.LatestModuleFile] pick the newest version by version order, as
+// given by [Metadata.SortedVersions], instead of by registry order.
+//
+// Use this for registries that do not list versions in ascending order.
+//
+// Default: registry order (the last listed version is the newest)
+func WithSortedVersions() Option {
+	return func(c *clientConfig) {
+		c.sortVersions = true
+	}
+}
+
+// WithValidateSourceIntegrity makes [Client.Source] check that the
+// integrity of the archive and of every patch is a well-formed
+// Subresource Integrity string, returning a [*ParseError] otherwise.
+//
+// This surfaces bad registry data when source.json is read rather than
+// when the archive is downloaded. An empty archive integrity is allowed,
+// since non-archive sources have none.
+//
+// Default: no validation
+func WithValidateSourceIntegrity() Option {
+	return func(c *clientConfig) {
+		c.validateIntegrity = true
+	}
+}
+
+// WithQueryParam adds a query parameter to every request URL, for
+// registries that authenticate with a key in the query string.
+//
+// The parameters are added to any query of the [WithBaseURL] URL. Query
+// parameters are omitted from URLs reported in errors so that keys are
+// not leaked into logs. The option may be repeated to add several
+// parameters.
+func WithQueryParam(key, value string) Option {
+	return func(c *clientConfig) {
+		if c.query == nil {
+			c.query = make(url.Values)
+		}
+		c.query.Add(key, value)
+	}
+}
+
+// maxRetryDelay caps the backoff between retries.
+const maxRetryDelay = time.Minute
+
+// WithRetry makes the client retry requests that fail transiently, as
+// reported by [Retryable]: the registry could not be reached, rate limited
+// the request or answered with a 5xx status. A request is sent at most
+// maxAttempts times.
+//
+// Retries back off exponentially from baseDelay, doubling after each
+// attempt up to one minute, with random jitter. A longer Retry-After from
+// a rate-limited response is honored within the same cap. Cancelling ctx
+// stops waiting immediately. Not-found responses are never retried.
+//
+// Default: no retries
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *clientConfig) {
+		c.retryAttempts = maxAttempts
+		c.retryDelay = baseDelay
+	}
+}
+
+// WithRateLimit limits the client to rps registry requests per second on
+// average, allowing bursts of up to burst requests. The limit is shared by
+// all goroutines using the client, and retries count against it. Waiting
+// for the limiter stops when the request's context is done.
+//
+// Default: no limit
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *clientConfig) {
+		c.rateLimit = rps
+		c.rateBurst = burst
+	}
+}
+
+// WithMaxConcurrency sets the number of requests batch operations such as
+// [Client.MetadataBatch] and [Client.SnapshotMetadata] make at once, and
+// the number of archives [Client.DownloadMany] downloads at once.
+//
+// Default: 8
+func WithMaxConcurrency(n int) Option {
+	return func(c *clientConfig) {
+		c.maxConcurrency = n
+	}
+}
+
+// WithLogger makes the client log each registry request, with its URL
+// and status code, each cache hit or miss, and each corrupt cache entry it
+// removes at debug level. Records carry module and version attributes
+// where they apply.
+//
+// Requests outside the registry, such as source archive downloads, are
+// logged as registry requests are, under their original URL when
+// [WithDownloadURLRewriter] is set.
+//
+// Default: no logging
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *clientConfig) {
+		c.logger = logger
+	}
+}
+
+// MetricEvent describes a completed registry fetch or cache hit. See
+// [WithMetricsHook].
+type MetricEvent struct {
+	// Path is the registry path fetched (e.g.,
+	// "modules/rules_go/metadata.json"), without the base URL. For
+	// requests outside the registry, such as source archive downloads,
+	// it is the full URL.
+	Path string
+
+	// StatusCode is the HTTP status of the final response, or zero if
+	// none was received or the result came from the cache.
+	StatusCode int
+
+	// Duration is the time the fetch took, including any retries. It
+	// is zero for cache hits.
+	Duration time.Duration
+
+	// CacheHit reports whether the result was served from the cache
+	// without contacting the registry.
+	CacheHit bool
+
+	// Err is the error the fetch failed with, or nil.
+	Err error
+}
+
+// WithMetricsHook sets a function called after every registry fetch
+// completes, successfully or not, and on every cache hit. It can be used
+// to feed metrics systems such as Prometheus.
+//
+// It is also called for each request outside the registry, such as a
+// source archive download, once the response headers arrive; the
+// transfer and verification of the body are not included.
+//
+// The hook is called synchronously, possibly from several goroutines at
+// once, and delays the request that triggered it; hooks doing heavy work
+// should hand events off to another goroutine.
+//
+// Default: none
+func WithMetricsHook(hook func(MetricEvent)) Option {
+	return func(c *clientConfig) {
+		c.metricsHook = hook
+	}
+}
+
+// WithNoRedirects stops the client from following HTTP redirects. A 3xx
+// response from the registry is returned as a [*RedirectError] carrying
+// the redirect target, so callers can audit it before acting on it.
+//
+// It only applies to registry requests. Source archive downloads, such as
+// GitHub release assets, still follow redirects; they are verified
+// against their integrity hashes instead.
+//
+// The HTTP client given to [WithHTTPClient] is copied, not modified.
+//
+// Default: redirects are followed
+func WithNoRedirects() Option {
+	return func(c *clientConfig) {
+		c.noRedirects = true
+	}
+}
+
+// WithAllowedSourceTypes restricts [Client.DownloadSource] and
+// [Client.DownloadMany] to versions whose source type, as given by
+// [Source.SourceType], is one of types (e.g., "archive"). Any other
+// version fails with a [*SourceTypeNotAllowedError] before its archive
+// is requested or any file is written. Later calls replace earlier ones.
+//
+// Default: all source types are allowed
+func WithAllowedSourceTypes(types ...string) Option {
+	return func(c *clientConfig) {
+		c.allowedSourceTypes = make(map[string]bool, len(types))
+		for _, t := range types {
+			c.allowedSourceTypes[t] = true
+		}
+	}
+}
+
+// WithAllowedDownloadHosts restricts [Client.DownloadSource] and
+// [Client.DownloadMany] to versions whose source is only fetched from the
+// given hosts (e.g., "github.com"), as checked by [Source.HostAllowed].
+// Any other version fails with a [*HostNotAllowedError] naming the host
+// before any archive is requested or file is written. Later calls replace
+// earlier ones.
+//
+// Hosts are those listed in source.json, before any rewrite by
+// [WithDownloadURLRewriter].
+//
+// Default: all hosts are allowed
+func WithAllowedDownloadHosts(hosts ...string) Option {
+	return func(c *clientConfig) {
+		c.allowedHosts = append([]string{}, hosts...)
+	}
+}
+
+// WithDownloadURLRewriter makes the client fetch source archives, and
+// other files outside the registry such as attestations, from
+// rewrite(url) instead of url. This routes downloads through an approved
+// proxy or mirror, e.g. by mapping "https://github.com/..." to
+// "https://proxy.example.com/github.com/...", without changing
+// source.json.
+//
+// Every candidate URL of [Source.AllURLs] is rewritten. Downloads are
+// still verified against the integrities in source.json, and errors
+// report the original URL.
+//
+// Default: URLs are fetched as given
+func WithDownloadURLRewriter(rewrite func(string) string) Option {
+	return func(c *clientConfig) {
+		c.rewriteURL = rewrite
+	}
+}
+
+// ResourceKind identifies the kind of registry resource an operation
+// fetches, for per-operation configuration such as
+// [WithOperationTimeout].
+type ResourceKind string
+
+// Resource kinds fetched by [Client].
+const (
+	// ResourceMetadata is a module's metadata.json.
+	ResourceMetadata ResourceKind = "metadata"
+
+	// ResourceSource is a version's source.json.
+	ResourceSource ResourceKind = "source"
+
+	// ResourceModuleFile is a version's MODULE.bazel.
+	ResourceModuleFile ResourceKind = "module_file"
+
+	// ResourceList is the module index used for listing.
+	ResourceList ResourceKind = "list"
+
+	// ResourceDownload is a source archive downloaded by
+	// [Client.DownloadSource] or an item of [Client.DownloadMany]. Its
+	// timeout covers the whole download, including the source.json
+	// fetch of DownloadSource.
+	ResourceDownload ResourceKind = "download"
+)
+
+// WithTimeout sets a default timeout for each call to a [Client] method,
+// covering all of its requests, retries and, for [Client.DownloadSource]
+// and each item of [Client.DownloadMany], the archive transfer.
+//
+// The timeout only applies when the caller's context has no deadline;
+// a caller-supplied deadline is never overridden, and cancelling the
+// caller's context still cancels the call. A [WithOperationTimeout] for
+// the kind of resource fetched takes precedence.
+//
+// It is independent of any Timeout set on the [http.Client] given to
+// [WithHTTPClient], which limits each request attempt separately; the
+// shorter of the two ends a request first.
+//
+// Default: no timeout
+func WithTimeout(d time.Duration) Option {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+// WithOperationTimeout sets a default timeout for operations fetching
+// the given kind of resource, overriding [WithTimeout] for them.
+//
+// The timeout only applies when the caller's context has no deadline;
+// a caller-supplied deadline is never overridden. This lets one client
+// serve quick metadata lookups and slower transfers with different
+// limits, e.g. a short timeout for [ResourceMetadata] and a long one for
+// [ResourceDownload].
+//
+// Default: the [WithTimeout] timeout
+func WithOperationTimeout(op ResourceKind, d time.Duration) Option {
+	return func(c *clientConfig) {
+		if c.opTimeouts == nil {
+			c.opTimeouts = make(map[ResourceKind]time.Duration)
+		}
+		c.opTimeouts[op] = d
+	}
+}
+
+// withOperationTimeout derives a context with the configured timeout for
+// op if ctx has no deadline of its own.
+func (c *Client) withOperationTimeout(ctx context.Context, op ResourceKind) (context.Context, context.CancelFunc) {
+	d, ok := c.opTimeouts[op]
+	if !ok || d <= 0 {
+		d = c.timeout
+	}
+	return withDefaultTimeout(ctx, d)
+}
+
+// withDefaultTimeout derives a context with timeout d if d is positive
+// and ctx has no deadline of its own.
+func withDefaultTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	if _, has := ctx.Deadline(); has {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
 // Metadata fetches module metadata from the registry.
 //
 // Returns [ErrNotFound] if the module does not exist.
 func (c *Client) Metadata(ctx context.Context, module string) (*Metadata, error) {
-	urlPath := path.Join("modules", module, "metadata.json")
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceMetadata)
+	defer cancel()
+
+	urlPath := c.pathMapper(module, "", "metadata.json")
 
 	// Check cache first
+	var stale []byte
 	if c.cache != nil {
-		if data, ok := c.cache.get(urlPath, true); ok {
+		if data, ok := c.cacheGet(ctx, urlPath, c.cacheTTL, module, ""); ok {
 			var meta Metadata
-			if err := json.Unmarshal(data, &meta); err == nil {
+			err := json.Unmarshal(data, &meta)
+			if err == nil {
 				return &meta, nil
 			}
+			// Corrupt entry (e.g., truncated by a crash); drop it so it
+			// does not outlive a failed refetch.
+			c.dropCorruptEntry(ctx, urlPath, module, "", err)
+		}
+		// An expired entry can still be revalidated by the registry.
+		if data, ok := c.cache.Get(urlPath, 0); ok && json.Valid(data) {
+			stale = data
 		}
 	}
 
-	data, err := c.fetch(ctx, urlPath, module, "")
+	var data []byte
+	var validators cacheValidators
+	var err error
+	if c.cache != nil {
+		data, validators, err = c.fetchRevalidate(ctx, urlPath, module, "", stale)
+	} else {
+		data, err = c.fetch(ctx, urlPath, module, "")
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	var meta Metadata
 	if err := json.Unmarshal(data, &meta); err != nil {
-		return nil, fmt.Errorf("bcr: failed to parse metadata for %s: %w", module, err)
+		return nil, &ParseError{Module: module, File: "metadata.json", Err: err}
 	}
 
 	// Cache the result
 	if c.cache != nil {
-		c.cache.set(urlPath, data)
+		c.cacheRevalidated(urlPath, data, validators)
 	}
 
 	return &meta, nil
@@ -158,15 +822,23 @@ func (c *Client) Metadata(ctx context.Context, module string) (*Metadata, error)
 //
 // Returns [ErrNotFound] if the module or version does not exist.
 func (c *Client) Source(ctx context.Context, module, version string) (*Source, error) {
-	urlPath := path.Join("modules", module, version, "source.json")
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceSource)
+	defer cancel()
+
+	urlPath := c.pathMapper(module, version, c.sourceFile)
 
 	// Check cache (source info is immutable, no TTL needed)
 	if c.cache != nil {
-		if data, ok := c.cache.get(urlPath, false); ok {
+		if data, ok := c.cacheGet(ctx, urlPath, 0, module, version); ok {
 			var src Source
-			if err := json.Unmarshal(data, &src); err == nil {
+			err := json.Unmarshal(data, &src)
+			if err == nil {
+				if err := c.checkSource(&src, module, version); err != nil {
+					return nil, err
+				}
 				return &src, nil
 			}
+			c.dropCorruptEntry(ctx, urlPath, module, version, err)
 		}
 	}
 
@@ -177,62 +849,454 @@ func (c *Client) Source(ctx context.Context, module, version string) (*Source, e
 
 	var src Source
 	if err := json.Unmarshal(data, &src); err != nil {
-		return nil, fmt.Errorf("bcr: failed to parse source for %s@%s: %w", module, version, err)
+		return nil, &ParseError{Module: module, Version: version, File: c.sourceFile, Err: err}
+	}
+	if err := c.checkSource(&src, module, version); err != nil {
+		return nil, err
 	}
 
 	// Cache the result
 	if c.cache != nil {
-		c.cache.set(urlPath, data)
+		c.cache.Set(urlPath, data)
 	}
 
 	return &src, nil
 }
 
-// ModuleFile fetches the MODULE.bazel content for a specific version.
-//
-// Returns [ErrNotFound] if the module or version does not exist.
-func (c *Client) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
-	urlPath := path.Join("modules", module, version, "MODULE.bazel")
-
-	// Check cache (immutable)
-	if c.cache != nil {
-		if data, ok := c.cache.get(urlPath, false); ok {
-			return data, nil
-		}
-	}
-
-	data, err := c.fetch(ctx, urlPath, module, version)
-	if err != nil {
-		return nil, err
+// checkSource validates decoded source information according to the
+// client's options.
+func (c *Client) checkSource(src *Source, module, version string) error {
+	if !c.validateIntegrity {
+		return nil
 	}
-
-	// Cache the result
-	if c.cache != nil {
-		c.cache.set(urlPath, data)
+	if err := validateSourceIntegrity(src); err != nil {
+		return &ParseError{Module: module, Version: version, File: c.sourceFile, Err: err}
 	}
-
-	return data, nil
+	return nil
 }
 
-// Latest returns the latest non-yanked version of a module.
+// SourceStrict is like [Client.Source], but first checks the module's
+// metadata and fails with a [*YankedError], matching [ErrYanked], if the
+// version is yanked. Use it where a yanked version must never be used.
 //
-// Returns [ErrNotFound] if the module does not exist or all versions are yanked.
-func (c *Client) Latest(ctx context.Context, module string) (string, error) {
+// Returns [ErrNotFound] if the module or version does not exist.
+func (c *Client) SourceStrict(ctx context.Context, module, version string) (*Source, error) {
 	meta, err := c.Metadata(ctx, module)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	latest := meta.Latest()
-	if latest == "" {
-		return "", &NotFoundError{Module: module}
+	if meta.IsYanked(version) {
+		return nil, &YankedError{Module: module, Version: version, Reason: meta.YankReason(version)}
 	}
-	return latest, nil
+	return c.Source(ctx, module, version)
 }
 
-// Versions returns an iterator over all versions of a module.
+// Resolve fetches everything needed to use a module version: its
+// metadata, source information and MODULE.bazel file. The version must be
+// listed in the metadata; if it is empty, the latest version is resolved
+// as by [Client.Latest]. Dev dependencies are left out of the result's
+// Deps, as they are when the module is not the root module.
 //
-// The iterator yields versions in registry order (oldest first).
+// Returns [ErrNotFound] if the module or version does not exist.
+func (c *Client) Resolve(ctx context.Context, module, version string) (*ResolvedModule, error) {
+	if version == "" {
+		latest, err := c.Latest(ctx, module)
+		if err != nil {
+			return nil, err
+		}
+		version = latest
+	} else {
+		meta, err := c.Metadata(ctx, module)
+		if err != nil {
+			return nil, err
+		}
+		if !meta.HasVersion(version) {
+			return nil, &NotFoundError{Module: module, Version: version}
+		}
+	}
+
+	src, err := c.Source(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	content, err := c.ModuleFile(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := ParseDeps(content, ExcludeDevDependencies())
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedModule{
+		Name:    module,
+		Version: version,
+		Source:  src,
+		Deps:    make(map[string]string, len(deps)),
+	}
+	for _, dep := range deps {
+		resolved.Deps[dep.Name] = dep.Version
+	}
+	return resolved, nil
+}
+
+// ModuleFile fetches the MODULE.bazel content for a specific version.
+//
+// Returns [ErrNotFound] if the module or version does not exist.
+func (c *Client) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceModuleFile)
+	defer cancel()
+
+	urlPath := c.pathMapper(module, version, c.moduleFile)
+
+	// Check cache (immutable)
+	if c.cache != nil {
+		if data, ok := c.cacheGet(ctx, urlPath, 0, module, version); ok {
+			return data, nil
+		}
+	}
+
+	data, err := c.fetch(ctx, urlPath, module, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the result
+	if c.cache != nil {
+		c.cache.Set(urlPath, data)
+	}
+
+	return data, nil
+}
+
+// ModuleFileReader is like [Client.ModuleFile], but returns a reader
+// streaming the MODULE.bazel file instead of its content. The caller must
+// close it.
+//
+// A cached copy is served from memory; otherwise the registry response
+// body is returned as it arrives and is not added to the cache. A timeout
+// set by [WithOperationTimeout] or [WithTimeout] lasts until the reader is
+// closed.
+func (c *Client) ModuleFileReader(ctx context.Context, module, version string) (_ io.ReadCloser, err error) {
+	urlPath := c.pathMapper(module, version, c.moduleFile)
+	if c.cache != nil {
+		if data, ok := c.cacheGet(ctx, urlPath, 0, module, version); ok {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceModuleFile)
+	var status int
+	defer c.observe(urlPath, time.Now(), &status, &err)
+
+	resp, _, err := c.do(ctx, http.MethodGet, urlPath, module, version, nil)
+	status = responseStatus(resp, err)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// cancelOnClose is a response body that releases its request's context
+// when closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// ModuleFileIntegrity fetches the MODULE.bazel file for a specific
+// version and returns its sha256 Subresource Integrity string.
+//
+// This is useful when re-hosting module files and recording their hashes.
+func (c *Client) ModuleFileIntegrity(ctx context.Context, module, version string) (string, error) {
+	data, err := c.ModuleFile(ctx, module, version)
+	if err != nil {
+		return "", err
+	}
+	return ComputeIntegrity(data), nil
+}
+
+// HasModuleBazel reports whether a MODULE.bazel file exists for a
+// specific version, without downloading its content.
+//
+// This distinguishes genuine Bzlmod modules from legacy WORKSPACE-only
+// entries. A 404 response is reported as false rather than an error.
+// Registries that reject HEAD are checked with a full [Client.ModuleFile]
+// request instead, as with [Client.Exists].
+func (c *Client) HasModuleBazel(ctx context.Context, module, version string) (bool, error) {
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceModuleFile)
+	defer cancel()
+
+	urlPath := c.pathMapper(module, version, c.moduleFile)
+
+	if c.cache != nil {
+		if _, ok := c.cacheGet(ctx, urlPath, 0, module, version); ok {
+			return true, nil
+		}
+	}
+
+	err := c.head(ctx, urlPath, module, version)
+	if isHeadUnsupported(err) {
+		_, err = c.ModuleFile(ctx, module, version)
+	}
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// BazelCompatibility returns the bazel_compatibility constraints declared
+// in the MODULE.bazel of a specific version (e.g., ">=7.0.0"), or nil if
+// it declares none. Use [ModuleInfo.SatisfiesBazel] to check a Bazel
+// version against them.
+//
+// Returns [ErrNotFound] if the module or version does not exist. An error
+// parsing the MODULE.bazel is returned as is.
+func (c *Client) BazelCompatibility(ctx context.Context, module, version string) ([]string, error) {
+	content, err := c.ModuleFile(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ParseModuleFile(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s@%s: %w", module, version, err)
+	}
+	return info.BazelCompatibility, nil
+}
+
+// InvalidateModule removes a module's cached metadata, so that the next
+// request for it goes to the registry. Cached version files are
+// immutable and are kept.
+//
+// It does nothing if caching is disabled.
+func (c *Client) InvalidateModule(module string) error {
+	if c.cache == nil {
+		return nil
+	}
+	key := c.pathMapper(module, "", "metadata.json")
+	c.cache.Delete(key)
+	c.cache.Delete(validatorsKey(key))
+	return nil
+}
+
+// InvalidateAll removes every entry from the cache, including the cache
+// directory's contents when [WithCacheDir] is used. It also drops the
+// module index kept by [Client.ListModulesPage].
+//
+// Nothing else is done if caching is disabled. A [Cache] given to
+// [WithCache] must provide a Clear() error method to support this;
+// otherwise an error is returned.
+func (c *Client) InvalidateAll() error {
+	c.pageIndex.Lock()
+	c.pageIndex.modules = nil
+	c.pageIndex.Unlock()
+
+	if c.cache == nil {
+		return nil
+	}
+	clearer, ok := c.cache.(cacheClearer)
+	if !ok {
+		return fmt.Errorf("bcr: cache %T does not support clearing", c.cache)
+	}
+	if err := clearer.Clear(); err != nil {
+		return fmt.Errorf("bcr: failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+// Latest returns the latest non-yanked version of a module, in registry
+// order unless [WithSortedVersions] is set.
+//
+// Returns [ErrNoVersions] if the module exists but lists no versions.
+// Returns [ErrNotFound] if the module does not exist or all versions are
+// yanked, unless [WithLatestFallbackToYanked] is set. Errors matching
+// ErrNoVersions also match ErrNotFound.
+func (c *Client) Latest(ctx context.Context, module string) (string, error) {
+	meta, err := c.Metadata(ctx, module)
+	if err != nil {
+		return "", err
+	}
+	if len(meta.Versions) == 0 {
+		return "", &NoVersionsError{Module: module}
+	}
+
+	latest := meta.latest(c.sortVersions)
+	if latest == "" && c.latestFallback {
+		versions := meta.orderedVersions(c.sortVersions)
+		latest = versions[len(versions)-1]
+	}
+	if latest == "" {
+		return "", &NotFoundError{Module: module}
+	}
+	return latest, nil
+}
+
+// LatestOrDefault is like [Client.Latest], but if the module has no
+// non-yanked version it returns fallback instead, after checking with
+// [Client.VersionExists] that the module has that version. This lets a
+// tool pin a known version of a module whose releases have all been
+// yanked.
+//
+// If fallback does not exist either, the error from Latest is returned.
+func (c *Client) LatestOrDefault(ctx context.Context, module, fallback string) (string, error) {
+	latest, err := c.Latest(ctx, module)
+	if err == nil || fallback == "" || !errors.Is(err, ErrNotFound) {
+		return latest, err
+	}
+	exists, existsErr := c.VersionExists(ctx, module, fallback)
+	if existsErr != nil {
+		return "", existsErr
+	}
+	if !exists {
+		return "", err
+	}
+	return fallback, nil
+}
+
+// LatestStable returns the latest stable version of a module, as chosen
+// by [Metadata.LatestStable], in registry order unless
+// [WithSortedVersions] is set.
+//
+// Returns [ErrNoVersions] if the module exists but lists no versions,
+// and [ErrNotFound] if the module does not exist or all versions are
+// yanked. Errors matching ErrNoVersions also match ErrNotFound.
+func (c *Client) LatestStable(ctx context.Context, module string) (string, error) {
+	meta, err := c.Metadata(ctx, module)
+	if err != nil {
+		return "", err
+	}
+	if len(meta.Versions) == 0 {
+		return "", &NoVersionsError{Module: module}
+	}
+
+	version := meta.latestStable(c.sortVersions)
+	if version == "" {
+		return "", &NotFoundError{Module: module}
+	}
+	return version, nil
+}
+
+// LatestCompatible returns the newest non-yanked version of a module whose
+// MODULE.bazel declares the given compatibility_level (0 if it declares
+// none), in registry order unless [WithSortedVersions] is set.
+//
+// Versions are checked newest first, fetching and parsing each one's
+// MODULE.bazel, and the search stops at the first match, so finding an
+// old level can take many requests. Returns [ErrNotFound] if no version
+// matches. An error fetching or parsing a MODULE.bazel is returned as is.
+func (c *Client) LatestCompatible(ctx context.Context, module string, level int) (string, error) {
+	meta, err := c.Metadata(ctx, module)
+	if err != nil {
+		return "", err
+	}
+
+	versions := meta.orderedVersions(c.sortVersions)
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if meta.IsYanked(v) {
+			continue
+		}
+		content, err := c.ModuleFile(ctx, module, v)
+		if err != nil {
+			return "", err
+		}
+		info, err := ParseModuleFile(content)
+		if err != nil {
+			return "", fmt.Errorf("%s@%s: %w", module, v, err)
+		}
+		if info.CompatibilityLevel == level {
+			return v, nil
+		}
+	}
+	return "", &NotFoundError{Module: module}
+}
+
+// SafeUpdate returns the newest non-yanked version of a module that is
+// newer than current and shares its compatibility_level, that is, an
+// upgrade Bazel treats as non-breaking. Versions are ordered as in
+// [Client.LatestCompatible]. found is false if current is already the
+// newest such version.
+//
+// The MODULE.bazel files of current and of every newer non-yanked version
+// are fetched concurrently, bounded by [WithMaxConcurrency], and cached
+// like [Client.ModuleFile] results. Returns [ErrNotFound] if the module
+// or current does not exist. An error fetching or parsing a MODULE.bazel
+// that could change the answer is returned.
+func (c *Client) SafeUpdate(ctx context.Context, module, current string) (version string, found bool, err error) {
+	meta, err := c.Metadata(ctx, module)
+	if err != nil {
+		return "", false, err
+	}
+	versions := meta.orderedVersions(c.sortVersions)
+	i := slices.Index(versions, current)
+	if i < 0 {
+		return "", false, &NotFoundError{Module: module, Version: current}
+	}
+
+	candidates := []string{current}
+	for _, v := range versions[i+1:] {
+		if !meta.IsYanked(v) {
+			candidates = append(candidates, v)
+		}
+	}
+	levels, failures := batch(ctx, c.maxConcurrency, candidates, func(ctx context.Context, v string) (int, error) {
+		content, err := c.ModuleFile(ctx, module, v)
+		if err != nil {
+			return 0, err
+		}
+		info, err := ParseModuleFile(content)
+		if err != nil {
+			return 0, fmt.Errorf("%s@%s: %w", module, v, err)
+		}
+		return info.CompatibilityLevel, nil
+	})
+	if err := failures[current]; err != nil {
+		return "", false, err
+	}
+
+	for _, v := range slices.Backward(candidates[1:]) {
+		if err := failures[v]; err != nil {
+			return "", false, err
+		}
+		if levels[v] == levels[current] {
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// LatestModuleFile fetches the MODULE.bazel content of the latest stable
+// version of a module, as chosen by [Metadata.LatestStable], and returns
+// it together with that version.
+//
+// Returns [ErrNoVersions] if the module lists no versions, and
+// [ErrNotFound] if the module does not exist or all versions are yanked.
+func (c *Client) LatestModuleFile(ctx context.Context, module string) (version string, content []byte, err error) {
+	version, err = c.LatestStable(ctx, module)
+	if err != nil {
+		return "", nil, err
+	}
+
+	content, err = c.ModuleFile(ctx, module, version)
+	if err != nil {
+		return "", nil, err
+	}
+	return version, content, nil
+}
+
+// Versions returns an iterator over all versions of a module.
+//
+// The iterator yields versions in registry order (oldest first).
 // Use [Metadata] if you need the full metadata including yank information.
 func (c *Client) Versions(ctx context.Context, module string) iter.Seq2[string, error] {
 	return func(yield func(string, error) bool) {
@@ -249,9 +1313,189 @@ func (c *Client) Versions(ctx context.Context, module string) iter.Seq2[string,
 	}
 }
 
+// VersionSources returns an iterator over all versions of a module paired
+// with their source information.
+//
+// Versions are yielded in registry order (oldest first). Each version's
+// source.json is fetched lazily as the iterator advances, so breaking out
+// of the loop stops further requests. If fetching a source fails, the
+// error is yielded with the version and iteration continues; if the
+// context is cancelled, its error is yielded and iteration stops.
+func (c *Client) VersionSources(ctx context.Context, module string) iter.Seq2[VersionSource, error] {
+	return func(yield func(VersionSource, error) bool) {
+		meta, err := c.Metadata(ctx, module)
+		if err != nil {
+			yield(VersionSource{}, err)
+			return
+		}
+		for _, v := range meta.Versions {
+			if err := ctx.Err(); err != nil {
+				yield(VersionSource{Version: v}, err)
+				return
+			}
+			src, err := c.Source(ctx, module, v)
+			if !yield(VersionSource{Version: v, Source: src}, err) {
+				return
+			}
+		}
+	}
+}
+
+// VersionBundle fetches the registry files of a specific version
+// concurrently: source.json and MODULE.bazel, plus presubmit.yml and
+// attestations.json when present.
+//
+// Missing optional files leave the corresponding fields nil. Returns
+// [ErrNotFound] only if source.json or MODULE.bazel is missing.
+func (c *Client) VersionBundle(ctx context.Context, module, version string) (*VersionBundle, error) {
+	b := &VersionBundle{Module: module, Version: version}
+	var (
+		wg                                   sync.WaitGroup
+		srcErr, modErr, presubmitErr, attErr error
+	)
+	wg.Go(func() { b.Source, srcErr = c.Source(ctx, module, version) })
+	wg.Go(func() { b.ModuleFile, modErr = c.ModuleFile(ctx, module, version) })
+	wg.Go(func() { b.Presubmit, presubmitErr = c.optionalFile(ctx, module, version, "presubmit.yml") })
+	wg.Go(func() { b.Attestations, attErr = c.optionalFile(ctx, module, version, "attestations.json") })
+	wg.Wait()
+
+	for _, err := range []error{srcErr, modErr, presubmitErr, attErr} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Presubmit fetches the presubmit.yml file of a specific version, which
+// describes the CI configurations the BCR tests the module with.
+// Returns [ErrNotFound] if the module, version or file does not exist.
+func (c *Client) Presubmit(ctx context.Context, module, version string) ([]byte, error) {
+	return c.versionFile(ctx, module, version, "presubmit.yml")
+}
+
+// Attestations fetches and parses the attestations.json file of a
+// specific version. Returns [ErrNotFound] if the module, version or file
+// does not exist; older versions often have no attestations.
+func (c *Client) Attestations(ctx context.Context, module, version string) (*Attestations, error) {
+	const name = "attestations.json"
+	data, err := c.versionFile(ctx, module, version, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var att Attestations
+	if err := json.Unmarshal(data, &att); err != nil {
+		if c.cache != nil {
+			c.cache.Delete(c.pathMapper(module, version, name))
+		}
+		return nil, &ParseError{Module: module, Version: version, File: name, Err: err}
+	}
+	return &att, nil
+}
+
+// optionalFile fetches a version file that registries may omit,
+// returning nil content rather than an error if it does not exist.
+func (c *Client) optionalFile(ctx context.Context, module, version, name string) ([]byte, error) {
+	data, err := c.versionFile(ctx, module, version, name)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// versionFile fetches a file in a version's directory, caching it as
+// immutable.
+func (c *Client) versionFile(ctx context.Context, module, version, name string) ([]byte, error) {
+	ctx, cancel := withDefaultTimeout(ctx, c.timeout)
+	defer cancel()
+
+	urlPath := c.pathMapper(module, version, name)
+
+	// Check cache (immutable)
+	if c.cache != nil {
+		if data, ok := c.cacheGet(ctx, urlPath, 0, module, version); ok {
+			return data, nil
+		}
+	}
+
+	data, err := c.fetch(ctx, urlPath, module, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cache != nil {
+		c.cache.Set(urlPath, data)
+	}
+	return data, nil
+}
+
+// ListVersionsOption configures [Client.ListVersions].
+type ListVersionsOption func(*listVersionsConfig)
+
+// listVersionsConfig holds configuration for a ListVersions call.
+type listVersionsConfig struct {
+	includeYanked bool
+}
+
+// IncludeYanked makes [Client.ListVersions] return yanked versions too.
+func IncludeYanked() ListVersionsOption {
+	return func(c *listVersionsConfig) {
+		c.includeYanked = true
+	}
+}
+
+// ListVersions returns the version strings of a module in ascending
+// version order, as by [Metadata.SortedVersions], regardless of the order
+// the registry lists them in.
+//
+// Yanked versions are omitted unless [IncludeYanked] is passed. The
+// underlying metadata is fetched with [Client.Metadata], so the cache is
+// respected.
+//
+// Returns [ErrNotFound] if the module does not exist.
+func (c *Client) ListVersions(ctx context.Context, module string, opts ...ListVersionsOption) ([]string, error) {
+	var cfg listVersionsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meta, err := c.Metadata(ctx, module)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(meta.Versions))
+	for _, v := range meta.SortedVersions() {
+		if !cfg.includeYanked && meta.IsYanked(v) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
 // Exists reports whether a module exists in the registry.
+//
+// Unless the module's metadata is cached, Exists sends a HEAD request for
+// metadata.json rather than downloading it. Registries that reject HEAD
+// with 405 Method Not Allowed or 501 Not Implemented are checked with a
+// full [Client.Metadata] request instead.
 func (c *Client) Exists(ctx context.Context, module string) (bool, error) {
-	_, err := c.Metadata(ctx, module)
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceMetadata)
+	defer cancel()
+
+	urlPath := c.pathMapper(module, "", "metadata.json")
+	if c.cache != nil {
+		if _, ok := c.cacheGet(ctx, urlPath, c.cacheTTL, module, ""); ok {
+			return true, nil
+		}
+	}
+
+	err := c.head(ctx, urlPath, module, "")
+	if isHeadUnsupported(err) {
+		_, err = c.Metadata(ctx, module)
+	}
 	if err != nil {
 		if isNotFound(err) {
 			return false, nil
@@ -261,8 +1505,43 @@ func (c *Client) Exists(ctx context.Context, module string) (bool, error) {
 	return true, nil
 }
 
+// ExistsBatch checks concurrently whether each of modules exists, as
+// [Client.Exists] does, and returns a map from module name to existence.
+// It is meant for validating long lists of dependencies.
+//
+// Requests are bounded as with [Client.MetadataBatch]. A module that does
+// not exist maps to false. Any other failure stops the remaining checks
+// and is returned, naming the module, with a nil map.
+func (c *Client) ExistsBatch(ctx context.Context, modules []string) (map[string]bool, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	exists, failures := batch(ctx, c.maxConcurrency, modules, func(ctx context.Context, module string) (bool, error) {
+		ok, err := c.Exists(ctx, module)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", module, err)
+			cancel(err)
+		}
+		return ok, err
+	})
+	if len(failures) > 0 {
+		// Every failure cancels ctx, so its cause is the first failure
+		// or the caller's own cancellation.
+		return nil, context.Cause(ctx)
+	}
+	return exists, nil
+}
+
 // VersionExists reports whether a specific version exists.
+//
+// By default the version is looked up in the module's metadata, which is
+// the registry's authoritative version list. With [WithHeadVersionExists],
+// a HEAD request for the version's source.json is sent instead.
 func (c *Client) VersionExists(ctx context.Context, module, version string) (bool, error) {
+	if c.headVersionExists {
+		return c.versionFileExists(ctx, module, version)
+	}
+
 	meta, err := c.Metadata(ctx, module)
 	if err != nil {
 		if isNotFound(err) {
@@ -276,9 +1555,25 @@ func (c *Client) VersionExists(ctx context.Context, module, version string) (boo
 // ListModules returns all available module names from the registry.
 //
 // This requires the registry to provide a modules/index.json file.
-// Returns [ErrListingNotSupported] if the index is not available.
+// Returns [ErrListingNotSupported] if the index is not available. When
+// caching is enabled, the index is cached like metadata and refetched
+// once older than the [WithCacheTTL] TTL.
 func (c *Client) ListModules(ctx context.Context) ([]string, error) {
-	urlPath := path.Join("modules", "index.json")
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceList)
+	defer cancel()
+
+	urlPath := c.pathMapper("", "", "index.json")
+
+	if c.cache != nil {
+		if data, ok := c.cacheGet(ctx, urlPath, c.cacheTTL, "", ""); ok {
+			var modules []string
+			err := json.Unmarshal(data, &modules)
+			if err == nil {
+				return modules, nil
+			}
+			c.dropCorruptEntry(ctx, urlPath, "", "", err)
+		}
+	}
 
 	data, err := c.fetch(ctx, urlPath, "", "")
 	if err != nil {
@@ -293,30 +1588,553 @@ func (c *Client) ListModules(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("bcr: failed to parse module index: %w", err)
 	}
 
+	if c.cache != nil {
+		c.cache.Set(urlPath, data)
+	}
+	return modules, nil
+}
+
+// ListModulesPage returns up to limit module names starting at offset,
+// in index order, along with the total number of modules, for paging
+// through the index in a UI. A limit of zero means no limit, and an
+// offset past the end returns no names.
+//
+// The index is listed as [Client.ListModules] does. Without a cache, the
+// client still keeps the index in memory for the [WithCacheTTL] TTL, so
+// that it is downloaded once rather than per page.
+func (c *Client) ListModulesPage(ctx context.Context, offset, limit int) ([]string, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, fmt.Errorf("bcr: invalid page: offset %d, limit %d must not be negative", offset, limit)
+	}
+	modules, err := c.pagedModules(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := min(offset, len(modules))
+	end := len(modules)
+	if limit > 0 && limit < end-start {
+		end = start + limit
+	}
+	return slices.Clone(modules[start:end]), len(modules), nil
+}
+
+// pagedModules returns the module index for [Client.ListModulesPage],
+// memoized for the cache TTL if the client has no cache.
+func (c *Client) pagedModules(ctx context.Context) ([]string, error) {
+	if c.cache != nil {
+		return c.ListModules(ctx)
+	}
+
+	c.pageIndex.Lock()
+	defer c.pageIndex.Unlock()
+	if c.pageIndex.modules != nil && time.Since(c.pageIndex.fetched) < c.cacheTTL {
+		return c.pageIndex.modules, nil
+	}
+	modules, err := c.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if modules == nil {
+		modules = []string{}
+	}
+	c.pageIndex.modules, c.pageIndex.fetched = modules, time.Now()
 	return modules, nil
 }
 
+// SnapshotMetadata lists every module and fetches all of their metadata
+// concurrently, returning a map from module name to metadata.
+//
+// Like [Client.ListModules], this requires modules/index.json and
+// returns [ErrListingNotSupported] if it is not available. If fetching
+// some modules fails, the map holds the modules that succeeded and the
+// returned error joins the per-module failures. Requests are bounded as
+// with [Client.MetadataBatch].
+func (c *Client) SnapshotMetadata(ctx context.Context) (map[string]*Metadata, error) {
+	modules, err := c.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, failures := c.MetadataBatch(ctx, modules)
+	var errs []error
+	for _, module := range modules {
+		if err, ok := failures[module]; ok {
+			errs = append(errs, fmt.Errorf("%s: %w", module, err))
+			delete(failures, module) // report duplicates once
+		}
+	}
+	return snapshot, errors.Join(errs...)
+}
+
+// ListModulesWithMetadata is like [Client.ListModules], but returns each
+// module's metadata along with its name, for tools such as dashboards
+// that show version counts. It is the same operation as
+// [Client.SnapshotMetadata].
+//
+// Every module's metadata is fetched, so against the BCR this makes one
+// request per module unless they are cached; the requests are bounded by
+// [WithMaxConcurrency] and each waits for a [WithRateLimit] token, so a
+// low rate limit makes the call take correspondingly long. Modules whose
+// metadata cannot be fetched are left out of the map and reported in the
+// returned error, which names each of them; the others are still
+// returned.
+func (c *Client) ListModulesWithMetadata(ctx context.Context) (map[string]*Metadata, error) {
+	return c.SnapshotMetadata(ctx)
+}
+
+// ModulesByMaintainer returns, in sorted order, the modules listing a
+// maintainer whose GitHub username is githubUser, compared
+// case-insensitively.
+//
+// This is expensive: it lists every module and fetches each one's
+// metadata, as [Client.SnapshotMetadata] does, so against the BCR it makes
+// one request per module unless they are cached. Requests are bounded as
+// with [Client.MetadataBatch]. If fetching some modules fails, the matches
+// among the rest are returned along with an error joining the failures.
+func (c *Client) ModulesByMaintainer(ctx context.Context, githubUser string) ([]string, error) {
+	snapshot, err := c.SnapshotMetadata(ctx)
+	if snapshot == nil {
+		return nil, err
+	}
+
+	var matches []string
+	for module, meta := range snapshot {
+		if slices.ContainsFunc(meta.Maintainers, func(m Maintainer) bool {
+			return m.GitHub != "" && strings.EqualFold(m.GitHub, githubUser)
+		}) {
+			matches = append(matches, module)
+		}
+	}
+	slices.Sort(matches)
+	return matches, err
+}
+
+// defaultMaxConcurrency is the default number of requests
+// [Client.MetadataBatch] makes at once.
+const defaultMaxConcurrency = 8
+
+// MetadataBatch fetches the metadata of many modules concurrently. It
+// returns the metadata of each module that succeeded and the error of each
+// module that failed; a module that does not exist fails with a
+// [*NotFoundError] without affecting the others.
+//
+// At most the number of requests set by [WithMaxConcurrency] are made at
+// once, and a rate limit set by [WithRateLimit] applies to each. If ctx is
+// cancelled, modules not yet started fail with the context's error.
+// Duplicate module names are fetched once.
+func (c *Client) MetadataBatch(ctx context.Context, modules []string) (map[string]*Metadata, map[string]error) {
+	return batch(ctx, c.maxConcurrency, modules, c.Metadata)
+}
+
+// SourcesForVersions fetches the source information of several versions
+// of a module concurrently, returning a map from version to source.
+//
+// Requests are bounded as with [Client.MetadataBatch]. If fetching some
+// versions fails, for example because they do not exist, the map holds
+// the versions that succeeded and the returned error joins the
+// per-version failures, each naming its version.
+func (c *Client) SourcesForVersions(ctx context.Context, module string, versions []string) (map[string]*Source, error) {
+	sources, failures := batch(ctx, c.maxConcurrency, versions, func(ctx context.Context, version string) (*Source, error) {
+		return c.Source(ctx, module, version)
+	})
+	var errs []error
+	for _, version := range versions {
+		if err, ok := failures[version]; ok {
+			errs = append(errs, fmt.Errorf("%s: %w", version, err))
+			delete(failures, version) // report duplicates once
+		}
+	}
+	return sources, errors.Join(errs...)
+}
+
+// batch calls fetch for each distinct key concurrently, at most limit at
+// a time, and returns the results and errors by key. If ctx is cancelled,
+// keys not yet started fail with the context's error.
+func batch[T any](ctx context.Context, limit int, keys []string, fetch func(context.Context, string) (T, error)) (map[string]T, map[string]error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]T, len(keys))
+		errs    = make(map[string]error)
+		sem     = make(chan struct{}, limit)
+		seen    = make(map[string]bool, len(keys))
+	)
+	fail := func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs[key] = err
+	}
+
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if err := ctx.Err(); err != nil {
+			fail(key, err)
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			fail(key, ctx.Err())
+			continue
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			result, err := fetch(ctx, key)
+			if err != nil {
+				fail(key, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[key] = result
+		})
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+// FindModules returns module names that start with prefix, compared
+// case-insensitively, in index order.
+//
+// At most limit names are returned; a limit of zero or less means no
+// limit. Like [Client.ListModules], this requires modules/index.json and
+// returns [ErrListingNotSupported] if it is not available.
+func (c *Client) FindModules(ctx context.Context, prefix string, limit int) ([]string, error) {
+	modules, err := c.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, name := range modules {
+		if !hasPrefixFold(name, prefix) {
+			continue
+		}
+		matches = append(matches, name)
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// SearchModules returns the module names containing query, compared
+// case-insensitively, in sorted order. It is meant for autocompletion;
+// use [Client.FindModules] to match only prefixes.
+//
+// Like [Client.ListModules], this requires modules/index.json and
+// returns [ErrListingNotSupported] if it is not available.
+func (c *Client) SearchModules(ctx context.Context, query string) ([]string, error) {
+	modules, err := c.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchNames(modules, query), nil
+}
+
+// searchNames returns the sorted names that contain query, ignoring case.
+func searchNames(names []string, query string) []string {
+	query = strings.ToLower(query)
+	var matches []string
+	for _, name := range names {
+		if strings.Contains(strings.ToLower(name), query) {
+			matches = append(matches, name)
+		}
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+// hasPrefixFold reports whether s begins with prefix, ignoring case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// cacheGet looks up key in the cache, logging whether it was found and
+// reporting hits to the metrics hook.
+func (c *Client) cacheGet(ctx context.Context, key string, maxAge time.Duration, module, version string) ([]byte, bool) {
+	data, ok := c.cache.Get(key, maxAge)
+	if ok && c.metricsHook != nil {
+		c.metricsHook(MetricEvent{Path: key, CacheHit: true})
+	}
+	if c.logger != nil {
+		msg := "bcr: cache miss"
+		if ok {
+			msg = "bcr: cache hit"
+		}
+		c.logger.LogAttrs(ctx, slog.LevelDebug, msg, slog.String("key", key), moduleAttrs(module, version))
+	}
+	return data, ok
+}
+
+// dropCorruptEntry removes a cache entry that failed to decode with err,
+// logging the removal at debug level.
+func (c *Client) dropCorruptEntry(ctx context.Context, key, module, version string, err error) {
+	c.cache.Delete(key)
+	if c.logger != nil {
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "bcr: dropped corrupt cache entry",
+			slog.String("key", key), moduleAttrs(module, version), slog.Any("error", err))
+	}
+}
+
+// observe reports a completed fetch of urlPath that started at start to
+// the metrics hook. It is deferred with pointers to the status and error
+// the fetch ends with.
+func (c *Client) observe(urlPath string, start time.Time, status *int, err *error) {
+	if c.metricsHook == nil {
+		return
+	}
+	c.metricsHook(MetricEvent{
+		Path:       urlPath,
+		StatusCode: *status,
+		Duration:   time.Since(start),
+		Err:        *err,
+	})
+}
+
+// logRequest logs a completed registry request at debug level.
+func (c *Client) logRequest(ctx context.Context, method, u, module, version string, status int, elapsed time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("url", u),
+		moduleAttrs(module, version),
+		slog.Duration("duration", elapsed),
+	}
+	if status != 0 {
+		attrs = append(attrs, slog.Int("status", status))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "bcr: request", attrs...)
+}
+
+// moduleAttrs returns the module and version log attributes, omitting
+// empty ones.
+func moduleAttrs(module, version string) slog.Attr {
+	var attrs []slog.Attr
+	if module != "" {
+		attrs = append(attrs, slog.String("module", module))
+	}
+	if version != "" {
+		attrs = append(attrs, slog.String("version", version))
+	}
+	// An empty group is dropped; inline the rest.
+	return slog.Attr{Value: slog.GroupValue(attrs...)}
+}
+
 // fetch makes an HTTP GET request and returns the response body.
-func (c *Client) fetch(ctx context.Context, urlPath, module, version string) ([]byte, error) {
-	u, err := url.JoinPath(c.baseURL, urlPath)
+func (c *Client) fetch(ctx context.Context, urlPath, module, version string) (_ []byte, err error) {
+	var status int
+	defer c.observe(urlPath, time.Now(), &status, &err)
+
+	resp, u, err := c.do(ctx, http.MethodGet, urlPath, module, version, nil)
+	status = responseStatus(resp, err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &RequestError{URL: u, Err: fmt.Errorf("failed to read response: %w", err)}
+	}
+
+	return data, nil
+}
+
+// cacheValidators are the response headers used to revalidate a stale
+// cache entry with a conditional request.
+type cacheValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validatorsKey returns the cache key under which the validators for the
+// entry at key are stored.
+func validatorsKey(key string) string {
+	return key + ".validators"
+}
+
+// fetchRevalidate is like [Client.fetch], but if stale holds an expired
+// cache entry for urlPath, it sends the validators stored with it. If the
+// registry answers 304 Not Modified, stale is returned as the body.
+//
+// The caller stores the returned body and validators with
+// [Client.cacheRevalidated] once it has checked the body.
+func (c *Client) fetchRevalidate(ctx context.Context, urlPath, module, version string, stale []byte) (_ []byte, _ cacheValidators, err error) {
+	var status int
+	defer c.observe(urlPath, time.Now(), &status, &err)
+
+	var hdr http.Header
+	var old cacheValidators
+	if stale != nil {
+		if raw, ok := c.cache.Get(validatorsKey(urlPath), 0); ok && json.Unmarshal(raw, &old) == nil {
+			hdr = make(http.Header)
+			if old.ETag != "" {
+				hdr.Set("If-None-Match", old.ETag)
+			}
+			if old.LastModified != "" {
+				hdr.Set("If-Modified-Since", old.LastModified)
+			}
+		}
+	}
+
+	resp, u, err := c.do(ctx, http.MethodGet, urlPath, module, version, hdr)
+	status = responseStatus(resp, err)
+	if err != nil {
+		return nil, cacheValidators{}, err
+	}
+	defer resp.Body.Close()
+
+	v := cacheValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		// A 304 may omit validators that have not changed.
+		if v.ETag == "" {
+			v.ETag = old.ETag
+		}
+		if v.LastModified == "" {
+			v.LastModified = old.LastModified
+		}
+		return stale, v, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cacheValidators{}, &RequestError{URL: u, Err: fmt.Errorf("failed to read response: %w", err)}
+	}
+	return data, v, nil
+}
+
+// cacheRevalidated stores a body returned by [Client.fetchRevalidate]
+// with its validators. Storing the body again also restarts its TTL.
+func (c *Client) cacheRevalidated(urlPath string, data []byte, v cacheValidators) {
+	c.cache.Set(urlPath, data)
+	if v == (cacheValidators{}) {
+		c.cache.Delete(validatorsKey(urlPath))
+		return
+	}
+	if raw, err := json.Marshal(v); err == nil {
+		c.cache.Set(validatorsKey(urlPath), raw)
+	}
+}
+
+// head makes an HTTP HEAD request, returning nil if the resource exists.
+func (c *Client) head(ctx context.Context, urlPath, module, version string) (err error) {
+	var status int
+	defer c.observe(urlPath, time.Now(), &status, &err)
+
+	resp, _, err := c.do(ctx, http.MethodHead, urlPath, module, version, nil)
+	status = responseStatus(resp, err)
 	if err != nil {
-		return nil, fmt.Errorf("bcr: invalid URL: %w", err)
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a request for urlPath with the extra headers in hdr, and
+// returns the response if its status is 200 OK, along with the request
+// URL. A 304 Not Modified response is also returned if hdr makes the
+// request conditional. Any other status is converted to an error and the
+// response body is closed.
+//
+// The request carries the query of the base URL together with the
+// parameters set with [WithQueryParam]. The returned URL, and any URL in
+// returned errors, omits the whole query so that credentials do not leak
+// into logs.
+func (c *Client) do(ctx context.Context, method, urlPath, module, version string, hdr http.Header) (*http.Response, string, error) {
+	if c.baseURLErr != nil {
+		return nil, "", c.baseURLErr
 	}
+	ref := c.baseURL.JoinPath(urlPath)
+	query := ref.Query()
+	for key, values := range c.query {
+		query[key] = append(query[key], values...)
+	}
+	ref.RawQuery = ""
+	u := ref.String()
+	ref.RawQuery = query.Encode()
+	reqURL := ref.String()
+
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := c.send(ctx, method, u, reqURL, module, version, hdr)
+		c.logRequest(ctx, method, u, module, version, responseStatus(resp, err), time.Since(start), err)
+		if err == nil || attempt >= c.retryAttempts || !Retryable(err) {
+			return resp, u, err
+		}
+
+		delay := retryDelay(c.retryDelay, attempt)
+		var rateErr *RateLimitError
+		if errors.As(err, &rateErr) && rateErr.RetryAfter > delay {
+			// Wait as long as the registry asked, within the usual cap.
+			delay = min(rateErr.RetryAfter, maxRetryDelay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, u, &RequestError{URL: u, Err: ctx.Err()}
+		}
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+// send makes a single attempt of a request built by [Client.do]. u is
+// reqURL without query parameters, used in errors.
+func (c *Client) send(ctx context.Context, method, u, reqURL, module, version string, hdr http.Header) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, &RequestError{URL: u, Err: err}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("bcr: failed to create request: %w", err)
 	}
+	for k, vs := range hdr {
+		req.Header[k] = vs
+	}
 	req.Header.Set("User-Agent", c.userAgent)
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
 	req.Header.Set("Accept", "application/json")
+	// Ask for compression explicitly rather than relying on the default
+	// transport, so that it also applies with a custom RoundTripper.
+	req.Header.Set("Accept-Encoding", "gzip")
 
 	resp, err := c.http.Do(req)
 	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = u // redact query parameters
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, &RequestError{URL: u, Err: ctxErr}
+		}
+		if isConnError(err) {
+			return nil, &RegistryUnavailableError{URL: u, Err: err}
+		}
 		return nil, &RequestError{URL: u, Err: err}
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
 		return nil, &NotFoundError{
 			Module:     module,
 			Version:    version,
@@ -324,96 +2142,245 @@ func (c *Client) fetch(ctx context.Context, urlPath, module, version string) ([]
 		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		resp.Body.Close()
+		return nil, &AuthError{URL: u, StatusCode: resp.StatusCode}
+	}
+
+	if isRedirect(resp.StatusCode) {
+		resp.Body.Close()
+		return nil, &RedirectError{
+			URL:        u,
+			Location:   resp.Header.Get("Location"),
+			StatusCode: resp.StatusCode,
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		return nil, &RateLimitError{
+			URL:        u,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	conditional := hdr.Get("If-None-Match") != "" || hdr.Get("If-Modified-Since") != ""
+	if resp.StatusCode != http.StatusOK && !(resp.StatusCode == http.StatusNotModified && conditional) {
+		resp.Body.Close()
 		return nil, &RequestError{URL: u, StatusCode: resp.StatusCode}
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	if method != http.MethodHead && resp.StatusCode == http.StatusOK {
+		if err := decompressBody(resp); err != nil {
+			resp.Body.Close()
+			return nil, &RequestError{URL: u, Err: err}
+		}
+	}
+	return resp, nil
+}
+
+// decompressBody replaces the body of a gzip-encoded response with its
+// decompressed content, as [http.Transport] does for requests it added
+// Accept-Encoding to.
+func decompressBody(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+	zr, err := gzip.NewReader(resp.Body)
 	if err != nil {
-		return nil, &RequestError{URL: u, Err: fmt.Errorf("failed to read response: %w", err)}
+		return fmt.Errorf("failed to decompress response: %w", err)
 	}
+	resp.Body = &gzipBody{Reader: zr, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
 
-	return data, nil
+// gzipBody is a decompressing response body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (b *gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
+
+// responseStatus returns the HTTP status of a request [Client.send] made,
+// or zero if no response was received.
+func responseStatus(resp *http.Response, err error) int {
+	if resp != nil {
+		return resp.StatusCode
+	}
+	var (
+		reqErr      *RequestError
+		notFoundErr *NotFoundError
+		redirectErr *RedirectError
+		rateErr     *RateLimitError
+		authErr     *AuthError
+	)
+	switch {
+	case errors.As(err, &rateErr):
+		return http.StatusTooManyRequests
+	case errors.As(err, &authErr):
+		return authErr.StatusCode
+	case errors.As(err, &reqErr):
+		return reqErr.StatusCode
+	case errors.As(err, &notFoundErr):
+		return notFoundErr.StatusCode
+	case errors.As(err, &redirectErr):
+		return redirectErr.StatusCode
+	}
+	return 0
+}
+
+// maxRetryAfter caps the delay parsed from a Retry-After header.
+const maxRetryAfter = 24 * time.Hour
+
+// parseRetryAfter parses a Retry-After header value, either a number of
+// seconds or an HTTP date, into a delay from now. It returns zero if the
+// value is missing, malformed or in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		// Cap absurd values rather than overflow.
+		return time.Duration(min(secs, int64(maxRetryAfter/time.Second))) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
 }
 
-// String returns the base URL of the registry.
+// retryDelay returns the backoff before retry number attempt (starting at
+// 1): base doubled for each earlier retry, with jitter that picks a
+// uniformly random delay between half and all of it.
+func retryDelay(base time.Duration, attempt int) time.Duration {
+	d := min(base, maxRetryDelay)
+	for i := 1; i < attempt && d < maxRetryDelay; i++ {
+		d = min(2*d, maxRetryDelay)
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// String returns the base URL of the registry, or an empty string if the
+// configured base URL is invalid.
 func (c *Client) String() string {
-	return c.baseURL
+	if c.baseURL == nil {
+		return ""
+	}
+	return c.baseURL.String()
 }
 
 // Type returns the registry type ("http" or "https").
 func (c *Client) Type() string {
-	if len(c.baseURL) >= 8 && c.baseURL[:8] == "https://" {
+	if c.baseURL != nil && c.baseURL.Scheme == "https" {
 		return "https"
 	}
 	return "http"
 }
 
-// isNotFound reports whether err indicates a not-found condition.
-func isNotFound(err error) bool {
-	if err == nil {
-		return false
-	}
-	if err == ErrNotFound {
-		return true
+// parseBaseURL validates a registry base URL given to [WithBaseURL] and
+// removes trailing slashes from its path.
+func parseBaseURL(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, errors.New("bcr: invalid base URL: empty")
 	}
-	if _, ok := err.(*NotFoundError); ok {
-		return true
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("bcr: invalid base URL: %w", err)
 	}
-	// Check wrapped errors
-	if u, ok := err.(interface{ Unwrap() error }); ok {
-		return isNotFound(u.Unwrap())
+	switch {
+	case u.Scheme == "" || u.Opaque != "": // e.g., "bcr.example.com" or "localhost:8080"
+		return nil, fmt.Errorf("bcr: invalid base URL %q: missing http:// or https:// scheme", s)
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, fmt.Errorf("bcr: invalid base URL %q: scheme must be http or https, got %s", s, u.Scheme)
+	case u.Host == "":
+		return nil, fmt.Errorf("bcr: invalid base URL %q: missing host", s)
 	}
-	return false
-}
-
-// --- Cache implementation ---
-
-type cache struct {
-	dir string
-	ttl time.Duration
-	mu  sync.RWMutex
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u, nil
 }
 
-func newCache(dir string, ttl time.Duration) *cache {
-	if ttl == 0 {
-		ttl = time.Hour
+// isConnError reports whether err is a connection-level failure, such as
+// a DNS lookup error or a refused connection, as opposed to a cancelled
+// request or a protocol error.
+func isConnError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
 	}
-	return &cache{dir: dir, ttl: ttl}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr)
 }
 
-func (c *cache) path(key string) string {
-	return filepath.Join(c.dir, filepath.FromSlash(key))
+// isRedirect reports whether status is a redirect response. 304 Not
+// Modified is not a redirect.
+func isRedirect(status int) bool {
+	return status >= 300 && status < 400 && status != http.StatusNotModified
 }
 
-func (c *cache) get(key string, checkTTL bool) ([]byte, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// versionFileExists reports whether the source.json of a version exists,
+// using a HEAD request unless it is cached.
+func (c *Client) versionFileExists(ctx context.Context, module, version string) (bool, error) {
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceSource)
+	defer cancel()
 
-	p := c.path(key)
-	info, err := os.Stat(p)
-	if err != nil {
-		return nil, false
+	urlPath := c.pathMapper(module, version, c.sourceFile)
+	if c.cache != nil {
+		if _, ok := c.cacheGet(ctx, urlPath, 0, module, version); ok {
+			return true, nil
+		}
 	}
 
-	if checkTTL && time.Since(info.ModTime()) > c.ttl {
-		return nil, false
+	err := c.head(ctx, urlPath, module, version)
+	if isHeadUnsupported(err) {
+		_, err = c.Source(ctx, module, version)
 	}
-
-	data, err := os.ReadFile(p)
 	if err != nil {
-		return nil, false
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
 	}
-	return data, true
+	return true, nil
 }
 
-func (c *cache) set(key string, data []byte) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// isHeadUnsupported reports whether err is a 405 or 501 response, as sent
+// by servers that do not support HEAD requests.
+func isHeadUnsupported(err error) bool {
+	var reqErr *RequestError
+	return errors.As(err, &reqErr) &&
+		(reqErr.StatusCode == http.StatusMethodNotAllowed || reqErr.StatusCode == http.StatusNotImplemented)
+}
 
-	p := c.path(key)
-	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
-		return // ignore cache write errors
+// isNotFound reports whether err indicates a not-found condition.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == ErrNotFound {
+		return true
 	}
-	_ = os.WriteFile(p, data, 0o644)
+	if _, ok := err.(*NotFoundError); ok {
+		return true
+	}
+	// Check wrapped errors
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return isNotFound(u.Unwrap())
+	}
+	return false
 }
@@ -1,13 +1,24 @@
 package bcr
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -15,8 +26,8 @@ import (
 func TestNew(t *testing.T) {
 	t.Run("defaults", func(t *testing.T) {
 		c := New()
-		if c.baseURL != DefaultBaseURL {
-			t.Errorf("baseURL = %q, want %q", c.baseURL, DefaultBaseURL)
+		if c.String() != DefaultBaseURL {
+			t.Errorf("baseURL = %q, want %q", c.String(), DefaultBaseURL)
 		}
 		if c.cache != nil {
 			t.Error("cache should be nil by default")
@@ -31,8 +42,8 @@ func TestNew(t *testing.T) {
 			WithUserAgent("test/1.0"),
 			WithCacheDir(t.TempDir()),
 		)
-		if c.baseURL != "https://example.com" {
-			t.Errorf("baseURL = %q, want %q", c.baseURL, "https://example.com")
+		if c.String() != "https://example.com" {
+			t.Errorf("baseURL = %q, want %q", c.String(), "https://example.com")
 		}
 		if c.http != customClient {
 			t.Error("http client not set correctly")
@@ -44,6 +55,35 @@ func TestNew(t *testing.T) {
 			t.Error("cache should not be nil")
 		}
 	})
+
+	t.Run("user agent suffix", func(t *testing.T) {
+		c := New(WithUserAgentSuffix("myapp/1.2.3"))
+		if c.userAgent != "go-bcr/1.0 myapp/1.2.3" {
+			t.Errorf("userAgent = %q, want %q", c.userAgent, "go-bcr/1.0 myapp/1.2.3")
+		}
+
+		c = New(WithUserAgent("custom/2.0"), WithUserAgentSuffix("myapp/1.2.3"))
+		if c.userAgent != "custom/2.0 myapp/1.2.3" {
+			t.Errorf("userAgent = %q, want %q", c.userAgent, "custom/2.0 myapp/1.2.3")
+		}
+	})
+}
+
+func TestUserAgentHeader(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithUserAgentSuffix("myapp/1.2.3"))
+	if _, err := c.Metadata(context.Background(), "testmod"); err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if got != "go-bcr/1.0 myapp/1.2.3" {
+		t.Errorf("User-Agent = %q, want %q", got, "go-bcr/1.0 myapp/1.2.3")
+	}
 }
 
 func TestMetadata(t *testing.T) {
@@ -173,6 +213,145 @@ func TestModuleFile(t *testing.T) {
 	}
 }
 
+func TestCustomFilenames(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/1.0.0/source":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		case "/modules/testmod/1.0.0/module.bzl":
+			w.Write([]byte(`module(name = "testmod")`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(
+		WithBaseURL(srv.URL),
+		WithSourceFilename("source"),
+		WithModuleFilename("module.bzl"),
+	)
+	ctx := context.Background()
+
+	src, err := c.Source(ctx, "testmod", "1.0.0")
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if src.URL != "https://example.com/archive.zip" {
+		t.Errorf("URL = %q, want %q", src.URL, "https://example.com/archive.zip")
+	}
+
+	content, err := c.ModuleFile(ctx, "testmod", "1.0.0")
+	if err != nil {
+		t.Fatalf("ModuleFile() error = %v", err)
+	}
+	if string(content) != `module(name = "testmod")` {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestHasModuleBazel(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.URL.Path == "/modules/testmod/1.0.0/MODULE.bazel" {
+			w.Write([]byte(`module(name = "testmod", version = "1.0.0")`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("exists", func(t *testing.T) {
+		methods = nil
+		ok, err := c.HasModuleBazel(ctx, "testmod", "1.0.0")
+		if err != nil {
+			t.Fatalf("HasModuleBazel() error = %v", err)
+		}
+		if !ok {
+			t.Error("HasModuleBazel() = false, want true")
+		}
+		if !slices.Equal(methods, []string{http.MethodHead}) {
+			t.Errorf("methods = %v, want [HEAD]", methods)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		ok, err := c.HasModuleBazel(ctx, "testmod", "9.9.9")
+		if err != nil {
+			t.Fatalf("HasModuleBazel() error = %v", err)
+		}
+		if ok {
+			t.Error("HasModuleBazel() = true, want false")
+		}
+	})
+
+	for _, status := range []int{http.StatusMethodNotAllowed, http.StatusNotImplemented} {
+		t.Run(fmt.Sprintf("falls back to GET on %d", status), func(t *testing.T) {
+			var methods []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				methods = append(methods, r.Method)
+				switch {
+				case r.Method == http.MethodHead:
+					w.WriteHeader(status)
+				case r.URL.Path == "/modules/testmod/1.0.0/MODULE.bazel":
+					w.Write([]byte(`module(name = "testmod", version = "1.0.0")`))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			c := New(WithBaseURL(srv.URL))
+			if ok, err := c.HasModuleBazel(ctx, "testmod", "1.0.0"); err != nil || !ok {
+				t.Errorf("HasModuleBazel(1.0.0) = %v, %v, want true", ok, err)
+			}
+			if ok, err := c.HasModuleBazel(ctx, "testmod", "9.9.9"); err != nil || ok {
+				t.Errorf("HasModuleBazel(9.9.9) = %v, %v, want false", ok, err)
+			}
+			want := []string{http.MethodHead, http.MethodGet, http.MethodHead, http.MethodGet}
+			if !slices.Equal(methods, want) {
+				t.Errorf("methods = %v, want %v", methods, want)
+			}
+		})
+	}
+}
+
+func TestBazelCompatibility(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/1.0.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod", version = "1.0.0", bazel_compatibility = [">=7.0.0", "<8"])`))
+		case "/modules/testmod/0.9.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod", version = "0.9.0")`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	got, err := c.BazelCompatibility(ctx, "testmod", "1.0.0")
+	if err != nil {
+		t.Fatalf("BazelCompatibility() error = %v", err)
+	}
+	if want := []string{">=7.0.0", "<8"}; !slices.Equal(got, want) {
+		t.Errorf("BazelCompatibility() = %q, want %q", got, want)
+	}
+
+	if got, err := c.BazelCompatibility(ctx, "testmod", "0.9.0"); err != nil || got != nil {
+		t.Errorf("BazelCompatibility() without constraints = %q, %v; want nil", got, err)
+	}
+	if _, err := c.BazelCompatibility(ctx, "testmod", "9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("BazelCompatibility() for missing version error = %v, want ErrNotFound", err)
+	}
+}
+
 func TestLatest(t *testing.T) {
 	meta := &Metadata{
 		Versions:       []string{"1.0.0", "1.1.0", "2.0.0"},
@@ -200,6 +379,132 @@ func TestLatest(t *testing.T) {
 	}
 }
 
+func TestLatestModuleFile(t *testing.T) {
+	meta := &Metadata{
+		Versions: []string{"1.0.0", "1.1.0", "2.0.0-rc1"},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(meta)
+		case "/modules/testmod/1.1.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod", version = "1.1.0")`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	version, content, err := c.LatestModuleFile(ctx, "testmod")
+	if err != nil {
+		t.Fatalf("LatestModuleFile() error = %v", err)
+	}
+	if version != "1.1.0" {
+		t.Errorf("version = %q, want %q (skipping prerelease)", version, "1.1.0")
+	}
+	if string(content) != `module(name = "testmod", version = "1.1.0")` {
+		t.Errorf("content = %q", content)
+	}
+
+	if _, _, err := c.LatestModuleFile(ctx, "nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLatestFallbackToYanked(t *testing.T) {
+	meta := &Metadata{
+		Versions:       []string{"1.0.0", "2.0.0"},
+		YankedVersions: map[string]string{"1.0.0": "bad", "2.0.0": "bad"},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/testmod/metadata.json" {
+			json.NewEncoder(w).Encode(meta)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	t.Run("strict by default", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL))
+		_, err := c.Latest(ctx, "testmod")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("with fallback", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithLatestFallbackToYanked())
+		got, err := c.Latest(ctx, "testmod")
+		if err != nil {
+			t.Fatalf("Latest() error = %v", err)
+		}
+		if got != "2.0.0" {
+			t.Errorf("Latest() = %q, want %q", got, "2.0.0")
+		}
+	})
+}
+
+func TestLatestNoVersions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/newmod/metadata.json":
+			w.Write([]byte(`{"versions": []}`))
+		case "/modules/yanked/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{
+				Versions:       []string{"1.0.0"},
+				YankedVersions: map[string]string{"1.0.0": "bad"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithLatestFallbackToYanked())
+	ctx := context.Background()
+
+	latestFuncs := map[string]func(context.Context, string) (string, error){
+		"Latest":       c.Latest,
+		"LatestStable": c.LatestStable,
+		"LatestModuleFile": func(ctx context.Context, module string) (string, error) {
+			v, _, err := c.LatestModuleFile(ctx, module)
+			return v, err
+		},
+	}
+
+	for name, latest := range latestFuncs {
+		t.Run(name, func(t *testing.T) {
+			_, err := latest(ctx, "newmod")
+			if !errors.Is(err, ErrNoVersions) {
+				t.Errorf("empty versions: error = %v, want ErrNoVersions", err)
+			}
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("empty versions: error = %v, want to also match ErrNotFound", err)
+			}
+
+			_, err = latest(ctx, "nonexistent")
+			if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrNoVersions) {
+				t.Errorf("missing module: error = %v, want ErrNotFound only", err)
+			}
+		})
+	}
+
+	t.Run("all yanked is not ErrNoVersions", func(t *testing.T) {
+		_, err := c.LatestStable(ctx, "yanked")
+		if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrNoVersions) {
+			t.Errorf("error = %v, want ErrNotFound only", err)
+		}
+	})
+}
+
 func TestVersions(t *testing.T) {
 	meta := &Metadata{
 		Versions: []string{"1.0.0", "1.1.0", "2.0.0"},
@@ -230,6 +535,120 @@ func TestVersions(t *testing.T) {
 	}
 }
 
+func TestVersionSources(t *testing.T) {
+	meta := &Metadata{
+		Versions: []string{"1.0.0", "1.1.0", "2.0.0"},
+	}
+
+	var sourceRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(meta)
+		case "/modules/testmod/1.0.0/source.json",
+			"/modules/testmod/1.1.0/source.json":
+			sourceRequests++
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/" + r.URL.Path})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("yields all versions", func(t *testing.T) {
+		var got []string
+		var errs int
+		for vs, err := range c.VersionSources(ctx, "testmod") {
+			got = append(got, vs.Version)
+			if err != nil {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("error = %v, want ErrNotFound", err)
+				}
+				errs++
+				continue
+			}
+			if vs.Source == nil || vs.Source.URL == "" {
+				t.Errorf("version %s: missing source", vs.Version)
+			}
+		}
+		if !slices.Equal(got, meta.Versions) {
+			t.Errorf("versions = %v, want %v", got, meta.Versions)
+		}
+		if errs != 1 {
+			t.Errorf("got %d errors, want 1 (2.0.0 has no source)", errs)
+		}
+	})
+
+	t.Run("early break stops fetching", func(t *testing.T) {
+		sourceRequests = 0
+		for range c.VersionSources(ctx, "testmod") {
+			break
+		}
+		if sourceRequests != 1 {
+			t.Errorf("sourceRequests = %d, want 1", sourceRequests)
+		}
+	})
+
+	t.Run("module not found", func(t *testing.T) {
+		for _, err := range c.VersionSources(ctx, "nonexistent") {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("error = %v, want ErrNotFound", err)
+			}
+		}
+	})
+}
+
+func TestListVersions(t *testing.T) {
+	meta := &Metadata{
+		Versions:       []string{"1.10.0", "1.0.0", "2.0.0", "1.1.0", "1.2.0"},
+		YankedVersions: map[string]string{"1.1.0": "broken"},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/testmod/metadata.json" {
+			json.NewEncoder(w).Encode(meta)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("excludes yanked by default", func(t *testing.T) {
+		got, err := c.ListVersions(ctx, "testmod")
+		if err != nil {
+			t.Fatalf("ListVersions() error = %v", err)
+		}
+		want := []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0"}
+		if !slices.Equal(got, want) {
+			t.Errorf("ListVersions() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("include yanked", func(t *testing.T) {
+		got, err := c.ListVersions(ctx, "testmod", IncludeYanked())
+		if err != nil {
+			t.Fatalf("ListVersions() error = %v", err)
+		}
+		want := []string{"1.0.0", "1.1.0", "1.2.0", "1.10.0", "2.0.0"}
+		if !slices.Equal(got, want) {
+			t.Errorf("ListVersions() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := c.ListVersions(ctx, "nonexistent")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
 func TestExists(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/modules/exists/metadata.json" {
@@ -324,6 +743,104 @@ func TestMetadataHelpers(t *testing.T) {
 	})
 }
 
+func TestStableVersions(t *testing.T) {
+	meta := &Metadata{
+		Versions:       []string{"1.10.0", "1.0.0", "1.1.0-rc1", "1.1.0", "1.2.0", "2.0.0-beta"},
+		YankedVersions: map[string]string{"1.1.0": "broken"},
+	}
+	want := []string{"1.0.0", "1.2.0", "1.10.0"}
+	if got := meta.StableVersions(); !slices.Equal(got, want) {
+		t.Errorf("StableVersions() = %v, want %v", got, want)
+	}
+
+	var nilMeta *Metadata
+	if got := nilMeta.StableVersions(); got != nil {
+		t.Errorf("nil.StableVersions() = %v, want nil", got)
+	}
+}
+
+func TestPrereleaseVersions(t *testing.T) {
+	meta := &Metadata{
+		// Out of order: listed as published, not by version.
+		Versions:       []string{"1.0.0", "2.0.0-beta", "1.1.0-rc1", "1.1.0-rc2", "1.1.0", "2.0.0-alpha.1"},
+		YankedVersions: map[string]string{"1.1.0-rc1": "broken"},
+	}
+	want := []string{"1.1.0-rc2", "2.0.0-alpha.1", "2.0.0-beta"}
+	if got := meta.PrereleaseVersions(); !slices.Equal(got, want) {
+		t.Errorf("PrereleaseVersions() = %v, want %v", got, want)
+	}
+
+	// Together with StableVersions, every non-yanked version is covered.
+	all := append(meta.StableVersions(), meta.PrereleaseVersions()...)
+	if len(all) != len(meta.Versions)-len(meta.YankedVersions) {
+		t.Errorf("StableVersions() + PrereleaseVersions() = %v, want every non-yanked version", all)
+	}
+
+	var nilMeta *Metadata
+	if got := nilMeta.PrereleaseVersions(); got != nil {
+		t.Errorf("nil.PrereleaseVersions() = %v, want nil", got)
+	}
+}
+
+func TestYankedVersionsDecode(t *testing.T) {
+	data := []byte(`{
+		"versions": ["1.0.0", "1.1.0", "2.0.0"],
+		"yanked_versions": {
+			"1.0.0": "security issue",
+			"1.1.0": {"reason": "broken build", "date": "2024-05-01T00:00:00Z"}
+		}
+	}`)
+
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(meta.Versions) != 3 {
+		t.Errorf("got %d versions, want 3", len(meta.Versions))
+	}
+
+	t.Run("string form", func(t *testing.T) {
+		if got := meta.YankReason("1.0.0"); got != "security issue" {
+			t.Errorf("YankReason(1.0.0) = %q, want %q", got, "security issue")
+		}
+		info, ok := meta.YankReasonDetailed("1.0.0")
+		if !ok || info.Reason != "security issue" || info.Date != "" {
+			t.Errorf("YankReasonDetailed(1.0.0) = %+v, %v", info, ok)
+		}
+	})
+
+	t.Run("object form", func(t *testing.T) {
+		if !meta.IsYanked("1.1.0") {
+			t.Error("IsYanked(1.1.0) = false, want true")
+		}
+		if got := meta.YankReason("1.1.0"); got != "broken build" {
+			t.Errorf("YankReason(1.1.0) = %q, want %q", got, "broken build")
+		}
+		info, ok := meta.YankReasonDetailed("1.1.0")
+		if !ok || info.Reason != "broken build" || info.Date != "2024-05-01T00:00:00Z" {
+			t.Errorf("YankReasonDetailed(1.1.0) = %+v, %v", info, ok)
+		}
+	})
+
+	t.Run("not yanked", func(t *testing.T) {
+		if _, ok := meta.YankReasonDetailed("2.0.0"); ok {
+			t.Error("YankReasonDetailed(2.0.0) ok = true, want false")
+		}
+		var nilMeta *Metadata
+		if _, ok := nilMeta.YankReasonDetailed("1.0.0"); ok {
+			t.Error("nil.YankReasonDetailed() ok = true, want false")
+		}
+	})
+
+	t.Run("invalid entry", func(t *testing.T) {
+		var m Metadata
+		err := json.Unmarshal([]byte(`{"versions": [], "yanked_versions": {"1.0.0": 42}}`), &m)
+		if err == nil {
+			t.Error("expected error for numeric yank entry")
+		}
+	})
+}
+
 func TestLatestStable(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -470,17 +987,114 @@ func TestCache(t *testing.T) {
 	}
 }
 
-func TestErrors(t *testing.T) {
-	t.Run("NotFoundError", func(t *testing.T) {
-		err := &NotFoundError{Module: "foo", Version: "1.0.0"}
-
-		// Check error message
-		msg := err.Error()
-		if msg != `bcr: module "foo" version "1.0.0" not found` {
-			t.Errorf("Error() = %q", msg)
-		}
+func TestCacheFutureModTime(t *testing.T) {
+	cacheDir := t.TempDir()
 
-		// Check Is
+	requestCount := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithCacheDir(cacheDir))
+	ctx := context.Background()
+
+	if _, err := c.Metadata(ctx, "skewed"); err != nil {
+		t.Fatalf("first Metadata() error = %v", err)
+	}
+
+	// Simulate the clock having jumped backward after the entry was written
+	cachePath := filepath.Join(cacheDir, "modules", "skewed", "metadata.json")
+	future := time.Now().Add(24 * time.Hour)
+	if err := os.Chtimes(cachePath, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Metadata(ctx, "skewed"); err != nil {
+		t.Fatalf("second Metadata() error = %v", err)
+	}
+	if requestCount != 2 {
+		t.Errorf("requestCount = %d, want 2 (future mtime should be stale)", requestCount)
+	}
+}
+
+func TestCacheCorruptEntry(t *testing.T) {
+	cacheDir := t.TempDir()
+	cachePath := filepath.Join(cacheDir, "modules", "corrupt", "metadata.json")
+	writeTruncated := func(t *testing.T) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(cachePath, []byte(`{"versions": ["1.0`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("replaced on next fetch", func(t *testing.T) {
+		writeTruncated(t)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		}))
+		defer srv.Close()
+
+		h := &recordingHandler{}
+		c := New(WithBaseURL(srv.URL), WithCacheDir(cacheDir), WithLogger(slog.New(h)))
+		meta, err := c.Metadata(ctx, "corrupt")
+		if err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if len(meta.Versions) != 1 {
+			t.Errorf("got %d versions, want 1", len(meta.Versions))
+		}
+		dropped := h.find("bcr: dropped corrupt cache entry")
+		if len(dropped) != 1 {
+			t.Fatalf("got %d dropped entry records, want 1", len(dropped))
+		}
+		if got := dropped[0]; got["key"] != "modules/corrupt/metadata.json" || got["module"] != "corrupt" || got["error"] == "" {
+			t.Errorf("dropped entry attrs = %v, want key, module and error", got)
+		}
+
+		data, err := os.ReadFile(cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cached Metadata
+		if err := json.Unmarshal(data, &cached); err != nil {
+			t.Errorf("cache entry still corrupt: %v", err)
+		}
+	})
+
+	t.Run("removed when refetch fails", func(t *testing.T) {
+		writeTruncated(t)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		c := New(WithBaseURL(srv.URL), WithCacheDir(cacheDir))
+		if _, err := c.Metadata(ctx, "corrupt"); err == nil {
+			t.Fatal("expected error from failing server")
+		}
+		if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+			t.Errorf("corrupt cache entry should have been removed, stat error = %v", err)
+		}
+	})
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("NotFoundError", func(t *testing.T) {
+		err := &NotFoundError{Module: "foo", Version: "1.0.0"}
+
+		// Check error message
+		msg := err.Error()
+		if msg != `bcr: module "foo" version "1.0.0" not found` {
+			t.Errorf("Error() = %q", msg)
+		}
+
+		// Check Is
 		if !errors.Is(err, ErrNotFound) {
 			t.Error("errors.Is(err, ErrNotFound) = false")
 		}
@@ -508,6 +1122,23 @@ func TestErrors(t *testing.T) {
 			t.Error("Unwrap() should return underlying error")
 		}
 	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		cause := errors.New("unexpected EOF")
+		err := &ParseError{Module: "foo", Version: "1.0.0", File: "source.json", Err: cause}
+		if msg := err.Error(); msg != "bcr: failed to parse source.json for foo@1.0.0: unexpected EOF" {
+			t.Errorf("Error() = %q", msg)
+		}
+
+		err2 := &ParseError{Module: "foo", File: "metadata.json", Err: cause}
+		if msg := err2.Error(); msg != "bcr: failed to parse metadata.json for foo: unexpected EOF" {
+			t.Errorf("Error() = %q", msg)
+		}
+
+		if !errors.Is(err, cause) {
+			t.Error("errors.Is(err, cause) = false")
+		}
+	})
 }
 
 func TestContextCancellation(t *testing.T) {
@@ -526,6 +1157,255 @@ func TestContextCancellation(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for cancelled context")
 	}
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) || reqErr.Err != context.Canceled {
+		t.Errorf("error = %v, want *RequestError wrapping context.Canceled", err)
+	}
+	if !IsCanceled(err) || IsTimeout(err) {
+		t.Errorf("IsCanceled() = %v, IsTimeout() = %v, want true, false", IsCanceled(err), IsTimeout(err))
+	}
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := c.Metadata(ctx, "test")
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.Err != context.DeadlineExceeded {
+			t.Errorf("error = %v, want *RequestError wrapping context.DeadlineExceeded", err)
+		}
+		if !IsTimeout(err) || IsCanceled(err) {
+			t.Errorf("IsTimeout() = %v, IsCanceled() = %v, want true, false", IsTimeout(err), IsCanceled(err))
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		if IsTimeout(nil) || IsCanceled(nil) {
+			t.Error("predicates should be false for nil")
+		}
+	})
+}
+
+func TestQueryParam(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.URL.Query().Get("key")
+		if r.URL.Path == "/modules/testmod/metadata.json" {
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithQueryParam("key", "s3cret"))
+	ctx := context.Background()
+
+	t.Run("sent with request", func(t *testing.T) {
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if gotKey != "s3cret" {
+			t.Errorf("key = %q, want %q", gotKey, "s3cret")
+		}
+	})
+
+	t.Run("redacted in status errors", func(t *testing.T) {
+		_, err := c.Metadata(ctx, "failing")
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) {
+			t.Fatalf("error = %v, want *RequestError", err)
+		}
+		if strings.Contains(reqErr.URL, "s3cret") || strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("error leaks key: %v", err)
+		}
+	})
+
+	t.Run("redacted in transport errors", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+
+		c := New(WithBaseURL(closed.URL), WithQueryParam("key", "s3cret"))
+		_, err := c.Metadata(ctx, "testmod")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("error leaks key: %v", err)
+		}
+	})
+
+	t.Run("base URL with query", func(t *testing.T) {
+		var gotQuery url.Values
+		var gotPath string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath, gotQuery = r.URL.Path, r.URL.Query()
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		c := New(WithBaseURL(srv.URL+"/registry?tenant=acme"), WithQueryParam("key", "s3cret"))
+		_, err := c.Metadata(ctx, "testmod")
+		if gotPath != "/registry/modules/testmod/metadata.json" {
+			t.Errorf("path = %q, want /registry/modules/testmod/metadata.json", gotPath)
+		}
+		if gotQuery.Get("tenant") != "acme" || gotQuery.Get("key") != "s3cret" || len(gotQuery) != 2 {
+			t.Errorf("query = %v, want tenant and key", gotQuery)
+		}
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.URL != srv.URL+"/registry/modules/testmod/metadata.json" {
+			t.Errorf("error = %v, want *RequestError for the URL without query", err)
+		}
+	})
+}
+
+func TestOperationTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/modules/testmod/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithOperationTimeout(ResourceMetadata, 10*time.Millisecond))
+
+	t.Run("applies to its kind", func(t *testing.T) {
+		_, err := c.Metadata(context.Background(), "testmod")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("other kinds unaffected", func(t *testing.T) {
+		if _, err := c.Source(context.Background(), "testmod", "1.0.0"); err != nil {
+			t.Errorf("Source() error = %v", err)
+		}
+	})
+
+	t.Run("caller deadline wins", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Errorf("Metadata() error = %v", err)
+		}
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/modules/testmod/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		case "/modules/testmod/1.0.0/presubmit.yml":
+			w.Write([]byte("tasks: {}\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithTimeout(10*time.Millisecond))
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		calls := map[string]func(ctx context.Context) error{
+			"Metadata": func(ctx context.Context) error {
+				_, err := c.Metadata(ctx, "testmod")
+				return err
+			},
+			"Source": func(ctx context.Context) error {
+				_, err := c.Source(ctx, "testmod", "1.0.0")
+				return err
+			},
+			"Presubmit": func(ctx context.Context) error {
+				_, err := c.Presubmit(ctx, "testmod", "1.0.0")
+				return err
+			},
+		}
+		for name, call := range calls {
+			if err := call(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%s() error = %v, want context.DeadlineExceeded", name, err)
+			}
+		}
+	})
+
+	t.Run("caller deadline wins", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Errorf("Metadata() error = %v", err)
+		}
+	})
+
+	t.Run("parent cancellation propagates", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithTimeout(5*time.Second))
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if _, err := c.Metadata(ctx, "testmod"); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("operation timeout takes precedence", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithTimeout(10*time.Millisecond), WithOperationTimeout(ResourceSource, 5*time.Second))
+		if _, err := c.Source(context.Background(), "testmod", "1.0.0"); err != nil {
+			t.Errorf("Source() error = %v", err)
+		}
+	})
+}
+
+func TestRegistryUnavailable(t *testing.T) {
+	t.Run("connection refused", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		url := srv.URL
+		srv.Close() // nothing listens on this address any more
+
+		c := New(WithBaseURL(url))
+		_, err := c.Metadata(context.Background(), "testmod")
+
+		var unavailable *RegistryUnavailableError
+		if !errors.As(err, &unavailable) {
+			t.Fatalf("error = %v (%T), want *RegistryUnavailableError", err, err)
+		}
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) {
+			t.Error("connection failure should not be a *RequestError")
+		}
+		if errors.Is(err, ErrNotFound) {
+			t.Error("connection failure should not match ErrNotFound")
+		}
+	})
+
+	t.Run("server error is not unavailable", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		c := New(WithBaseURL(srv.URL))
+		_, err := c.Metadata(context.Background(), "testmod")
+
+		var unavailable *RegistryUnavailableError
+		if errors.As(err, &unavailable) {
+			t.Error("HTTP 500 should not be *RegistryUnavailableError")
+		}
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("error = %v, want *RequestError with status 500", err)
+		}
+	})
 }
 
 func TestClientString(t *testing.T) {
@@ -537,6 +1417,9 @@ func TestClientString(t *testing.T) {
 		{"default", DefaultBaseURL, DefaultBaseURL},
 		{"custom https", "https://example.com/registry", "https://example.com/registry"},
 		{"custom http", "http://localhost:8080", "http://localhost:8080"},
+		{"trailing slash", "http://localhost:8080/", "http://localhost:8080"},
+		{"trailing slashes with path", "https://example.com/registry//", "https://example.com/registry"},
+		{"invalid", "example.com", ""},
 	}
 
 	for _, tt := range tests {
@@ -570,3 +1453,1565 @@ func TestClientType(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseURLValidation(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	for _, base := range []string{srv.URL + "/", srv.URL + "/registry/"} {
+		paths = nil
+		if _, err := New(WithBaseURL(base)).Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("base %q: Metadata() error = %v", base, err)
+		}
+		want := strings.TrimPrefix(base, srv.URL) + "modules/testmod/metadata.json"
+		if len(paths) != 1 || paths[0] != want {
+			t.Errorf("base %q: requested %q, want %q", base, paths, want)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		baseURL string
+		wantErr string
+	}{
+		{"empty", "", "empty"},
+		{"host without scheme", "host-without-scheme", "missing http:// or https:// scheme"},
+		{"host and port without scheme", "localhost:8080", "missing http:// or https:// scheme"},
+		{"other scheme", "ftp://example.com", "scheme must be http or https"},
+		{"missing host", "https:///registry", "missing host"},
+		{"unparsable", "http://exa mple.com", "invalid base URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(WithBaseURL(tt.baseURL))
+			_, err := c.Metadata(ctx, "testmod")
+			if err == nil || !strings.HasPrefix(err.Error(), "bcr: invalid base URL") || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Metadata() error = %v, want invalid base URL error containing %q", err, tt.wantErr)
+			}
+			if Retryable(err) {
+				t.Errorf("Retryable(%v) = true", err)
+			}
+		})
+	}
+}
+
+func TestNoRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			http.Redirect(w, r, "https://mirror.example.com/modules/testmod/metadata.json", http.StatusFound)
+		case "/modules/moved/metadata.json":
+			http.Redirect(w, r, "/modules/testmod/real.json", http.StatusMovedPermanently)
+		case "/modules/testmod/real.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/releases/download/v1.0.0/archive.zip":
+			http.Redirect(w, r, "/assets/archive.zip", http.StatusFound)
+		case "/assets/archive.zip":
+			w.Write([]byte("archive"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	hc := &http.Client{}
+	c := New(WithBaseURL(srv.URL), WithHTTPClient(hc), WithNoRedirects())
+	ctx := context.Background()
+
+	t.Run("redirect is an error", func(t *testing.T) {
+		_, err := c.Metadata(ctx, "testmod")
+		var redirErr *RedirectError
+		if !errors.As(err, &redirErr) {
+			t.Fatalf("error = %v (%T), want *RedirectError", err, err)
+		}
+		if redirErr.StatusCode != http.StatusFound {
+			t.Errorf("StatusCode = %d, want %d", redirErr.StatusCode, http.StatusFound)
+		}
+		if redirErr.Location != "https://mirror.example.com/modules/testmod/metadata.json" {
+			t.Errorf("Location = %q", redirErr.Location)
+		}
+	})
+
+	t.Run("archive downloads follow", func(t *testing.T) {
+		ref := ModuleRef{Name: "testmod", Version: "1.0.0"}
+		items := []DownloadItem{{ref, &Source{
+			URL:       srv.URL + "/releases/download/v1.0.0/archive.zip",
+			Integrity: ComputeIntegrity([]byte("archive")),
+		}}}
+		if err := c.DownloadMany(ctx, items, t.TempDir())[ref]; err != nil {
+			t.Errorf("DownloadMany() error = %v, want the archive redirect followed", err)
+		}
+	})
+
+	t.Run("caller client untouched", func(t *testing.T) {
+		if hc.CheckRedirect != nil {
+			t.Error("WithNoRedirects modified the caller's http.Client")
+		}
+	})
+
+	t.Run("default follows", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL))
+		m, err := c.Metadata(ctx, "moved")
+		if err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if m.Latest() != "1.0.0" {
+			t.Errorf("Latest() = %q, want %q", m.Latest(), "1.0.0")
+		}
+	})
+}
+
+func TestModuleFileIntegrity(t *testing.T) {
+	content := []byte(`module(name = "testmod", version = "1.0.0")`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/testmod/1.0.0/MODULE.bazel" {
+			w.Write(content)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	got, err := c.ModuleFileIntegrity(ctx, "testmod", "1.0.0")
+	if err != nil {
+		t.Fatalf("ModuleFileIntegrity() error = %v", err)
+	}
+	if want := ComputeIntegrity(content); got != want {
+		t.Errorf("ModuleFileIntegrity() = %q, want %q", got, want)
+	}
+
+	if _, err := c.ModuleFileIntegrity(ctx, "testmod", "9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestVersionBundle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/1.0.0/source.json", "/modules/testmod/2.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		case "/modules/testmod/1.0.0/MODULE.bazel", "/modules/testmod/2.0.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod")`))
+		case "/modules/testmod/1.0.0/presubmit.yml":
+			w.Write([]byte("matrix:\n  platform: [ubuntu2004]\n"))
+		case "/modules/testmod/1.0.0/attestations.json":
+			w.Write([]byte(`{"mediaType": "application/vnd.build.bazel.registry.attestation+json;version=1.0.0"}`))
+		case "/modules/testmod/3.0.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod")`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("all files", func(t *testing.T) {
+		b, err := c.VersionBundle(ctx, "testmod", "1.0.0")
+		if err != nil {
+			t.Fatalf("VersionBundle() error = %v", err)
+		}
+		if b.Module != "testmod" || b.Version != "1.0.0" {
+			t.Errorf("bundle = %s@%s, want testmod@1.0.0", b.Module, b.Version)
+		}
+		if b.Source == nil || b.Source.URL != "https://example.com/archive.zip" {
+			t.Errorf("Source = %+v", b.Source)
+		}
+		if string(b.ModuleFile) != `module(name = "testmod")` {
+			t.Errorf("ModuleFile = %q", b.ModuleFile)
+		}
+		if !strings.Contains(string(b.Presubmit), "ubuntu2004") {
+			t.Errorf("Presubmit = %q", b.Presubmit)
+		}
+		if len(b.Attestations) == 0 {
+			t.Error("Attestations should be set")
+		}
+	})
+
+	t.Run("optional files absent", func(t *testing.T) {
+		b, err := c.VersionBundle(ctx, "testmod", "2.0.0")
+		if err != nil {
+			t.Fatalf("VersionBundle() error = %v", err)
+		}
+		if b.Presubmit != nil || b.Attestations != nil {
+			t.Errorf("Presubmit = %q, Attestations = %q, want nil", b.Presubmit, b.Attestations)
+		}
+	})
+
+	t.Run("core file missing", func(t *testing.T) {
+		_, err := c.VersionBundle(ctx, "testmod", "3.0.0")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestSortedVersionsOption(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/testmod/metadata.json" {
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"2.0.0", "1.0.0", "1.10.0", "3.0.0-rc1"}})
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	t.Run("registry order by default", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL))
+		if got, _ := c.Latest(ctx, "testmod"); got != "3.0.0-rc1" {
+			t.Errorf("Latest() = %q, want %q", got, "3.0.0-rc1")
+		}
+		if got, _ := c.LatestStable(ctx, "testmod"); got != "1.10.0" {
+			t.Errorf("LatestStable() = %q, want %q", got, "1.10.0")
+		}
+	})
+
+	t.Run("sorted", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithSortedVersions())
+		if got, _ := c.Latest(ctx, "testmod"); got != "3.0.0-rc1" {
+			t.Errorf("Latest() = %q, want %q", got, "3.0.0-rc1")
+		}
+		if got, _ := c.LatestStable(ctx, "testmod"); got != "2.0.0" {
+			t.Errorf("LatestStable() = %q, want %q", got, "2.0.0")
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	// newFlaky returns a server that answers status for the first n
+	// requests and succeeds afterwards, and a pointer to its request count.
+	newFlaky := func(t *testing.T, n, status int) (*httptest.Server, *atomic.Int32) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if int(calls.Add(1)) <= n {
+				w.WriteHeader(status)
+				return
+			}
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		}))
+		t.Cleanup(srv.Close)
+		return srv, &calls
+	}
+	ctx := context.Background()
+
+	t.Run("succeeds after failures", func(t *testing.T) {
+		srv, calls := newFlaky(t, 2, http.StatusServiceUnavailable)
+		c := New(WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if got := calls.Load(); got != 3 {
+			t.Errorf("requests = %d, want 3", got)
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		srv, calls := newFlaky(t, 5, http.StatusBadGateway)
+		c := New(WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+		_, err := c.Metadata(ctx, "testmod")
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusBadGateway {
+			t.Errorf("error = %v, want *RequestError with status 502", err)
+		}
+		if got := calls.Load(); got != 3 {
+			t.Errorf("requests = %d, want 3", got)
+		}
+	})
+
+	t.Run("default no retry", func(t *testing.T) {
+		srv, calls := newFlaky(t, 1, http.StatusInternalServerError)
+		c := New(WithBaseURL(srv.URL))
+		if _, err := c.Metadata(ctx, "testmod"); err == nil {
+			t.Fatal("Metadata() should fail without retries")
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("requests = %d, want 1", got)
+		}
+	})
+
+	for _, status := range []int{http.StatusNotFound, http.StatusBadRequest} {
+		t.Run(fmt.Sprintf("no retry on %d", status), func(t *testing.T) {
+			srv, calls := newFlaky(t, 1, status)
+			c := New(WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+			if _, err := c.Metadata(ctx, "testmod"); err == nil {
+				t.Fatal("Metadata() should fail")
+			}
+			if got := calls.Load(); got != 1 {
+				t.Errorf("requests = %d, want 1", got)
+			}
+		})
+	}
+
+	t.Run("connection error", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		url := srv.URL
+		srv.Close()
+
+		c := New(WithBaseURL(url), WithRetry(2, time.Millisecond))
+		_, err := c.Metadata(ctx, "testmod")
+		var unavailable *RegistryUnavailableError
+		if !errors.As(err, &unavailable) {
+			t.Errorf("error = %v, want *RegistryUnavailableError", err)
+		}
+	})
+
+	t.Run("cancelled during backoff", func(t *testing.T) {
+		srv, calls := newFlaky(t, 5, http.StatusServiceUnavailable)
+		c := New(WithBaseURL(srv.URL), WithRetry(5, time.Hour))
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := c.Metadata(ctx, "testmod")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Metadata() took %v, want it to stop at the deadline", elapsed)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("requests = %d, want 1", got)
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt, want := range []time.Duration{base, 2 * base, 4 * base, 8 * base} {
+		for range 20 {
+			got := retryDelay(base, attempt+1)
+			if got < want/2 || got > want {
+				t.Fatalf("retryDelay(%v, %d) = %v, want between %v and %v", base, attempt+1, got, want/2, want)
+			}
+		}
+	}
+	if got := retryDelay(time.Second, 100); got > maxRetryDelay {
+		t.Errorf("retryDelay(1s, 100) = %v, want at most %v", got, maxRetryDelay)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+	}))
+	defer srv.Close()
+
+	const (
+		rps      = 50
+		burst    = 2
+		requests = 12
+	)
+	c := New(WithBaseURL(srv.URL), WithRateLimit(rps, burst))
+	ctx := context.Background()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Go(func() {
+			if _, err := c.Metadata(ctx, fmt.Sprintf("mod%d", i)); err != nil {
+				t.Errorf("Metadata() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// After the burst, each request waits for a new token.
+	minElapsed := time.Duration(requests-burst) * time.Second / rps
+	if elapsed := time.Since(start); elapsed < minElapsed*9/10 {
+		t.Errorf("%d requests took %v, want at least %v", requests, elapsed, minElapsed)
+	}
+	if len(times) != requests {
+		t.Fatalf("server saw %d requests, want %d", len(times), requests)
+	}
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithRateLimit(0.001, 1))
+		if _, err := c.Metadata(ctx, "first"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := c.Metadata(ctx, "second")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+}
+
+func TestPresubmit(t *testing.T) {
+	content := []byte(`matrix:
+  platform: ["debian10", "macos", "windows"]
+  bazel: ["7.x", "8.x"]
+tasks:
+  verify_targets:
+    name: Verify build targets
+    platform: ${{ platform }}
+    bazel: ${{ bazel }}
+    build_targets:
+      - "@testmod//..."
+`)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/modules/testmod/1.0.0/presubmit.yml" {
+			w.Write(content)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()))
+	ctx := context.Background()
+
+	for range 2 {
+		got, err := c.Presubmit(ctx, "testmod", "1.0.0")
+		if err != nil {
+			t.Fatalf("Presubmit() error = %v", err)
+		}
+		if string(got) != string(content) {
+			t.Errorf("Presubmit() = %q, want %q", got, content)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1 (second call should hit the cache)", got)
+	}
+
+	var notFound *NotFoundError
+	if _, err := c.Presubmit(ctx, "testmod", "9.9.9"); !errors.As(err, &notFound) || notFound.Version != "9.9.9" {
+		t.Errorf("error = %v, want *NotFoundError for 9.9.9", err)
+	}
+}
+
+func TestAttestations(t *testing.T) {
+	payload := `{
+  "mediaType": "application/vnd.build.bazel.registry.attestation+json;version=1.0.0",
+  "attestations": {
+    "source.json": {
+      "url": "https://github.com/owner/testmod/releases/download/v1.0.0/source.json.intoto.jsonl",
+      "integrity": "sha256-GGEW1gTfIbvNtp9GEDMYB9oUTA+cFJmoezEef/da3sI="
+    },
+    "MODULE.bazel": {
+      "url": "https://github.com/owner/testmod/releases/download/v1.0.0/MODULE.bazel.intoto.jsonl",
+      "integrity": "sha256-JbPu3Vd8ddLvO3hD9dZYISoPxMS3tG/8kFH/ZS8ywDs="
+    },
+    "testmod-v1.0.0.tar.gz": {
+      "url": "https://github.com/owner/testmod/releases/download/v1.0.0/testmod-v1.0.0.tar.gz.intoto.jsonl",
+      "integrity": "sha256-fuzF8ou00y0v6U4W93r4yIDSbBbSLdpo6VuSPhc/E1w="
+    }
+  }
+}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/1.0.0/attestations.json":
+			w.Write([]byte(payload))
+		case "/modules/broken/1.0.0/attestations.json":
+			w.Write([]byte(`{"attestations": [}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	att, err := c.Attestations(ctx, "testmod", "1.0.0")
+	if err != nil {
+		t.Fatalf("Attestations() error = %v", err)
+	}
+	if att.MediaType != "application/vnd.build.bazel.registry.attestation+json;version=1.0.0" {
+		t.Errorf("MediaType = %q", att.MediaType)
+	}
+	if len(att.Attestations) != 3 {
+		t.Errorf("got %d attestations, want 3", len(att.Attestations))
+	}
+	archive := att.Attestations["testmod-v1.0.0.tar.gz"]
+	if !strings.HasSuffix(archive.URL, "testmod-v1.0.0.tar.gz.intoto.jsonl") {
+		t.Errorf("archive URL = %q", archive.URL)
+	}
+	if err := validateIntegrity(archive.Integrity); err != nil {
+		t.Errorf("archive integrity %q: %v", archive.Integrity, err)
+	}
+
+	if _, err := c.Attestations(ctx, "testmod", "0.1.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing: error = %v, want ErrNotFound", err)
+	}
+	var parseErr *ParseError
+	if _, err := c.Attestations(ctx, "broken", "1.0.0"); !errors.As(err, &parseErr) || parseErr.File != "attestations.json" {
+		t.Errorf("broken: error = %v, want *ParseError for attestations.json", err)
+	}
+}
+
+// recordingHandler is a slog.Handler that keeps every record.
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+// find returns the attributes of the records with message msg.
+func (h *recordingHandler) find(msg string) []map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var found []map[string]string
+	for _, r := range h.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := make(map[string]string)
+		var add func(slog.Attr)
+		add = func(a slog.Attr) {
+			if a.Value.Kind() == slog.KindGroup {
+				for _, g := range a.Value.Group() {
+					add(g)
+				}
+				return
+			}
+			attrs[a.Key] = a.Value.String()
+		}
+		r.Attrs(func(a slog.Attr) bool { add(a); return true })
+		found = append(found, attrs)
+	}
+	return found
+}
+
+func TestLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/testmod/1.0.0/MODULE.bazel" {
+			w.Write([]byte(`module(name = "testmod")`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	h := &recordingHandler{}
+	c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()), WithLogger(slog.New(h)))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.ModuleFile(ctx, "testmod", "1.0.0"); err != nil {
+			t.Fatalf("ModuleFile() error = %v", err)
+		}
+	}
+	c.ModuleFile(ctx, "testmod", "9.9.9")
+
+	if misses := h.find("bcr: cache miss"); len(misses) != 2 {
+		t.Errorf("got %d cache miss records, want 2", len(misses))
+	}
+	hits := h.find("bcr: cache hit")
+	if len(hits) != 1 {
+		t.Fatalf("got %d cache hit records, want 1", len(hits))
+	}
+	if hits[0]["module"] != "testmod" || hits[0]["version"] != "1.0.0" {
+		t.Errorf("cache hit attrs = %v, want module and version", hits[0])
+	}
+
+	requests := h.find("bcr: request")
+	if len(requests) != 2 {
+		t.Fatalf("got %d request records, want 2", len(requests))
+	}
+	if got := requests[0]; got["status"] != "200" || got["url"] != srv.URL+"/modules/testmod/1.0.0/MODULE.bazel" {
+		t.Errorf("first request attrs = %v", got)
+	}
+	if got := requests[1]; got["status"] != "404" || got["version"] != "9.9.9" || got["error"] == "" {
+		t.Errorf("second request attrs = %v", got)
+	}
+}
+
+// recordingTransport records the path and headers of every request it
+// forwards.
+type recordingTransport struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	paths   []string
+	headers []http.Header
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.paths = append(rt.paths, req.URL.Path)
+	rt.headers = append(rt.headers, req.Header.Clone())
+	rt.mu.Unlock()
+
+	// Add a header without disturbing the client's own.
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Trace-Id", "trace-123")
+	return rt.next.RoundTrip(req)
+}
+
+func TestTransport(t *testing.T) {
+	var gotTrace, gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTrace, gotUA = r.Header.Get("X-Trace-Id"), r.Header.Get("User-Agent")
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/modules/testmod/1.0.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod")`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	rt := &recordingTransport{next: http.DefaultTransport}
+	hc := &http.Client{Timeout: time.Minute}
+	c := New(WithBaseURL(srv.URL), WithHTTPClient(hc), WithTransport(rt), WithUserAgent("custom-agent"))
+	ctx := context.Background()
+
+	if _, err := c.Metadata(ctx, "testmod"); err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if _, err := c.ModuleFile(ctx, "testmod", "1.0.0"); err != nil {
+		t.Fatalf("ModuleFile() error = %v", err)
+	}
+
+	want := []string{"/modules/testmod/metadata.json", "/modules/testmod/1.0.0/MODULE.bazel"}
+	if !slices.Equal(rt.paths, want) {
+		t.Errorf("transport saw %q, want %q", rt.paths, want)
+	}
+	if ua := rt.headers[0].Get("User-Agent"); ua != "custom-agent" {
+		t.Errorf("transport saw User-Agent %q, want %q", ua, "custom-agent")
+	}
+	if gotTrace != "trace-123" || gotUA != "custom-agent" {
+		t.Errorf("server saw X-Trace-Id %q and User-Agent %q", gotTrace, gotUA)
+	}
+	if hc.Transport != nil {
+		t.Error("WithTransport modified the caller's http.Client")
+	}
+	if c.http.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want the WithHTTPClient timeout kept", c.http.Timeout)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	const token = "s3cret-token"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); ok && user == "alice" && pass == "hunter2" {
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	t.Run("bearer token", func(t *testing.T) {
+		h := &recordingHandler{}
+		rt := &recordingTransport{next: http.DefaultTransport}
+		c := New(WithBaseURL(srv.URL), WithBearerToken(token), WithUserAgent("custom-agent"),
+			WithTransport(rt), WithLogger(slog.New(h)))
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if got := rt.headers[0].Get("Authorization"); got != "Bearer "+token {
+			t.Errorf("Authorization = %q, want the bearer token", got)
+		}
+		if got := rt.headers[0].Get("User-Agent"); got != "custom-agent" {
+			t.Errorf("User-Agent = %q, want %q", got, "custom-agent")
+		}
+		for _, attrs := range h.find("bcr: request") {
+			for k, v := range attrs {
+				if strings.Contains(v, token) {
+					t.Errorf("log attribute %s = %q contains the token", k, v)
+				}
+			}
+		}
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithBasicAuth("alice", "hunter2"))
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithBearerToken("wrong"), WithRetry(3, time.Millisecond))
+		_, err := c.Metadata(ctx, "testmod")
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("error = %v, want ErrUnauthorized", err)
+		}
+		var authErr *AuthError
+		if !errors.As(err, &authErr) || authErr.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("error = %#v, want *AuthError with status 401", err)
+		}
+		if authErr.URL != srv.URL+"/modules/testmod/metadata.json" {
+			t.Errorf("URL = %q", authErr.URL)
+		}
+		if strings.Contains(err.Error(), "wrong") {
+			t.Errorf("error %q contains the token", err)
+		}
+	})
+}
+
+func TestMetricsHook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/modules/broken/metadata.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var (
+		mu     sync.Mutex
+		events []MetricEvent
+	)
+	hook := func(e MetricEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()), WithMetricsHook(hook))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+	}
+	c.Metadata(ctx, "broken")
+	c.Source(ctx, "testmod", "9.9.9")
+
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(events), events)
+	}
+
+	live := events[0]
+	if live.Path != "modules/testmod/metadata.json" || live.StatusCode != http.StatusOK || live.CacheHit || live.Err != nil {
+		t.Errorf("live fetch event = %+v", live)
+	}
+	if live.Duration <= 0 {
+		t.Errorf("live fetch Duration = %v, want > 0", live.Duration)
+	}
+
+	hit := events[1]
+	if hit.Path != "modules/testmod/metadata.json" || !hit.CacheHit || hit.StatusCode != 0 || hit.Err != nil {
+		t.Errorf("cache hit event = %+v", hit)
+	}
+
+	var reqErr *RequestError
+	if e := events[2]; e.StatusCode != http.StatusInternalServerError || !errors.As(e.Err, &reqErr) {
+		t.Errorf("server error event = %+v", e)
+	}
+	if e := events[3]; e.StatusCode != http.StatusNotFound || !errors.Is(e.Err, ErrNotFound) {
+		t.Errorf("not found event = %+v", e)
+	}
+}
+
+func TestExistsHead(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+		written int
+	)
+	newServer := func(t *testing.T, allowHead bool) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			methods = append(methods, r.Method)
+			mu.Unlock()
+			if r.Method == http.MethodHead && !allowHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.URL.Path != "/modules/testmod/metadata.json" {
+				http.NotFound(w, r)
+				return
+			}
+			n, _ := w.Write([]byte(`{"versions": ["1.0.0"]}`))
+			mu.Lock()
+			if r.Method == http.MethodGet {
+				written += n
+			}
+			mu.Unlock()
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	reset := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		methods, written = nil, 0
+	}
+	ctx := context.Background()
+
+	t.Run("head", func(t *testing.T) {
+		reset()
+		c := New(WithBaseURL(newServer(t, true).URL))
+		if ok, err := c.Exists(ctx, "testmod"); err != nil || !ok {
+			t.Errorf("Exists(testmod) = %v, %v, want true", ok, err)
+		}
+		if ok, err := c.Exists(ctx, "missing"); err != nil || ok {
+			t.Errorf("Exists(missing) = %v, %v, want false", ok, err)
+		}
+		if !slices.Equal(methods, []string{http.MethodHead, http.MethodHead}) {
+			t.Errorf("methods = %v, want two HEAD requests", methods)
+		}
+		if written != 0 {
+			t.Errorf("server wrote %d body bytes, want 0", written)
+		}
+	})
+
+	t.Run("falls back to GET on 405", func(t *testing.T) {
+		reset()
+		c := New(WithBaseURL(newServer(t, false).URL))
+		if ok, err := c.Exists(ctx, "testmod"); err != nil || !ok {
+			t.Errorf("Exists(testmod) = %v, %v, want true", ok, err)
+		}
+		if ok, err := c.Exists(ctx, "missing"); err != nil || ok {
+			t.Errorf("Exists(missing) = %v, %v, want false", ok, err)
+		}
+		want := []string{http.MethodHead, http.MethodGet, http.MethodHead, http.MethodGet}
+		if !slices.Equal(methods, want) {
+			t.Errorf("methods = %v, want %v", methods, want)
+		}
+	})
+
+	t.Run("cached metadata", func(t *testing.T) {
+		reset()
+		c := New(WithBaseURL(newServer(t, true).URL), WithCache(NewMemoryCache()))
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if ok, err := c.Exists(ctx, "testmod"); err != nil || !ok {
+			t.Errorf("Exists(testmod) = %v, %v, want true", ok, err)
+		}
+		if !slices.Equal(methods, []string{http.MethodGet}) {
+			t.Errorf("methods = %v, want only the Metadata GET", methods)
+		}
+	})
+}
+
+func TestHeadVersionExists(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			w.Write([]byte(`{"versions": ["1.0.0"]}`))
+		case "/modules/testmod/1.0.0/source.json", "/modules/testmod/2.0.0/source.json":
+			// 2.0.0 has files but is not listed in the metadata yet.
+			w.Write([]byte(`{"url": "https://example.com/testmod.tar.gz"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    map[string]bool
+		request string
+	}{
+		{"metadata", nil, map[string]bool{"1.0.0": true, "2.0.0": false, "9.9.9": false}, "GET /modules/testmod/metadata.json"},
+		{"head", []Option{WithHeadVersionExists()}, map[string]bool{"1.0.0": true, "2.0.0": true, "9.9.9": false}, "HEAD /modules/testmod/1.0.0/source.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			requests = nil
+			mu.Unlock()
+
+			c := New(append([]Option{WithBaseURL(srv.URL)}, tt.opts...)...)
+			for _, version := range []string{"1.0.0", "2.0.0", "9.9.9"} {
+				ok, err := c.VersionExists(ctx, "testmod", version)
+				if err != nil {
+					t.Fatalf("VersionExists(%s) error = %v", version, err)
+				}
+				if ok != tt.want[version] {
+					t.Errorf("VersionExists(%s) = %v, want %v", version, ok, tt.want[version])
+				}
+			}
+			if requests[0] != tt.request {
+				t.Errorf("first request = %q, want %q", requests[0], tt.request)
+			}
+		})
+	}
+
+	t.Run("head missing module", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithHeadVersionExists())
+		if ok, err := c.VersionExists(ctx, "missing", "1.0.0"); err != nil || ok {
+			t.Errorf("VersionExists() = %v, %v, want false", ok, err)
+		}
+	})
+}
+
+func TestRateLimitError(t *testing.T) {
+	retryAfter := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		header   string
+		min, max time.Duration
+	}{
+		{"seconds", "120", 120 * time.Second, 120 * time.Second},
+		{"http date", time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat), 80 * time.Second, 90 * time.Second},
+		{"date in the past", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+		{"missing", "", 0, 0},
+		{"malformed", "soon", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryAfter = tt.header
+			_, err := c.Metadata(ctx, "testmod")
+			if !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("error = %v, want ErrRateLimited", err)
+			}
+			var rateErr *RateLimitError
+			if !errors.As(err, &rateErr) {
+				t.Fatalf("error = %T, want *RateLimitError", err)
+			}
+			if rateErr.RetryAfter < tt.min || rateErr.RetryAfter > tt.max {
+				t.Errorf("RetryAfter = %v, want between %v and %v", rateErr.RetryAfter, tt.min, tt.max)
+			}
+			if rateErr.URL != srv.URL+"/modules/testmod/metadata.json" {
+				t.Errorf("URL = %q", rateErr.URL)
+			}
+		})
+	}
+
+	t.Run("retried", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		}))
+		defer srv.Close()
+
+		c := New(WithBaseURL(srv.URL), WithRetry(2, time.Millisecond))
+		if _, err := c.Metadata(ctx, "testmod"); err != nil {
+			t.Errorf("Metadata() error = %v", err)
+		}
+	})
+}
+
+func TestAuthError(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		t.Run(fmt.Sprint(status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			c := New(WithBaseURL(srv.URL))
+			_, err := c.Source(context.Background(), "testmod", "1.0.0")
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Fatalf("error = %v, want ErrUnauthorized", err)
+			}
+			var authErr *AuthError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("error = %T, want *AuthError", err)
+			}
+			if authErr.StatusCode != status || authErr.URL != srv.URL+"/modules/testmod/1.0.0/source.json" {
+				t.Errorf("AuthError = %+v", authErr)
+			}
+			if errors.Unwrap(err) != nil {
+				t.Errorf("Unwrap() = %v, want nil", errors.Unwrap(err))
+			}
+			var reqErr *RequestError
+			if errors.As(err, &reqErr) || errors.Is(err, ErrNotFound) || Retryable(err) {
+				t.Errorf("error = %v, want only an auth error", err)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"server error", &RequestError{URL: "u", StatusCode: 503}, true},
+		{"client error", &RequestError{URL: "u", StatusCode: 400}, false},
+		{"rate limited", &RateLimitError{URL: "u", RetryAfter: time.Second}, true},
+		{"unavailable", &RegistryUnavailableError{URL: "u", Err: errors.New("connection refused")}, true},
+		{"network timeout", &RequestError{URL: "u", Err: timeoutError{}}, true},
+		{"not found", &NotFoundError{Module: "m"}, false},
+		{"parse error", &ParseError{Module: "m", Err: errors.New("bad json")}, false},
+		{"integrity", &IntegrityError{Expected: "sha256-a", Actual: "sha256-b"}, false},
+		{"canceled", &RequestError{URL: "u", Err: context.Canceled}, false},
+		{"deadline", &RequestError{URL: "u", Err: context.DeadlineExceeded}, false},
+		{"wrapped server error", fmt.Errorf("fetch: %w", &RequestError{URL: "u", StatusCode: 500}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestOrDefault(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/yanked/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{
+				Versions:       []string{"1.0.0", "1.1.0"},
+				YankedVersions: map[string]string{"1.0.0": "bad", "1.1.0": "worse"},
+			})
+		case "/modules/healthy/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0", "2.0.0"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("latest available", func(t *testing.T) {
+		got, err := c.LatestOrDefault(ctx, "healthy", "1.0.0")
+		if err != nil || got != "2.0.0" {
+			t.Errorf("LatestOrDefault() = %q, %v; want 2.0.0", got, err)
+		}
+	})
+
+	t.Run("all yanked", func(t *testing.T) {
+		got, err := c.LatestOrDefault(ctx, "yanked", "1.0.0")
+		if err != nil || got != "1.0.0" {
+			t.Errorf("LatestOrDefault() = %q, %v; want fallback 1.0.0", got, err)
+		}
+	})
+
+	t.Run("fallback missing", func(t *testing.T) {
+		got, err := c.LatestOrDefault(ctx, "yanked", "3.0.0")
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) || notFound.Module != "yanked" || notFound.Version != "" {
+			t.Errorf("LatestOrDefault() = %q, %v; want the Latest *NotFoundError", got, err)
+		}
+	})
+
+	t.Run("module missing", func(t *testing.T) {
+		if _, err := c.LatestOrDefault(ctx, "nonexistent", "1.0.0"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestModuleFileReader(t *testing.T) {
+	const content = "module(name = \"testmod\", version = \"1.0.0\")\n"
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/modules/testmod/1.0.0/MODULE.bazel" {
+			w.Write([]byte(content))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	cache := NewMemoryCache()
+	c := New(WithBaseURL(srv.URL), WithCache(cache), WithTimeout(5*time.Second))
+	ctx := context.Background()
+
+	read := func() string {
+		t.Helper()
+		rc, err := c.ModuleFileReader(ctx, "testmod", "1.0.0")
+		if err != nil {
+			t.Fatalf("ModuleFileReader() error = %v", err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("reading: %v", err)
+		}
+		return string(data)
+	}
+
+	if got := read(); got != content {
+		t.Errorf("streamed content = %q, want %q", got, content)
+	}
+	if _, ok := cache.Get("modules/testmod/1.0.0/MODULE.bazel", 0); ok {
+		t.Error("streamed content should not be cached")
+	}
+
+	// Once ModuleFile has cached it, the reader is served from the cache.
+	if _, err := c.ModuleFile(ctx, "testmod", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	before := requests.Load()
+	if got := read(); got != content {
+		t.Errorf("cached content = %q, want %q", got, content)
+	}
+	if n := requests.Load() - before; n != 0 {
+		t.Errorf("cached read made %d requests, want 0", n)
+	}
+
+	if _, err := c.ModuleFileReader(ctx, "testmod", "9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPathMapper(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/tenants/acme/modules/index.json":
+			json.NewEncoder(w).Encode([]string{"testmod"})
+		case "/tenants/acme/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		case "/tenants/acme/modules/testmod/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/testmod.tar.gz"})
+		case "/tenants/acme/modules/testmod/1.0.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod")`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cache := NewMemoryCache()
+	c := New(WithBaseURL(srv.URL), WithCache(cache), WithPathMapper(func(module, version, file string) string {
+		return "tenants/acme/" + DefaultPathMapper(module, version, file)
+	}))
+	ctx := context.Background()
+
+	if _, err := c.ListModules(ctx); err != nil {
+		t.Errorf("ListModules() error = %v", err)
+	}
+	if _, err := c.Metadata(ctx, "testmod"); err != nil {
+		t.Errorf("Metadata() error = %v", err)
+	}
+	if _, err := c.Source(ctx, "testmod", "1.0.0"); err != nil {
+		t.Errorf("Source() error = %v", err)
+	}
+	if _, err := c.ModuleFile(ctx, "testmod", "1.0.0"); err != nil {
+		t.Errorf("ModuleFile() error = %v", err)
+	}
+
+	want := []string{
+		"/tenants/acme/modules/index.json",
+		"/tenants/acme/modules/testmod/metadata.json",
+		"/tenants/acme/modules/testmod/1.0.0/source.json",
+		"/tenants/acme/modules/testmod/1.0.0/MODULE.bazel",
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("requested paths = %v, want %v", paths, want)
+	}
+
+	// Cache keys follow the mapped paths, and invalidation finds them.
+	key := "tenants/acme/modules/testmod/metadata.json"
+	if _, ok := cache.Get(key, 0); !ok {
+		t.Errorf("metadata not cached under %q", key)
+	}
+	if err := c.InvalidateModule("testmod"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(key, 0); ok {
+		t.Error("InvalidateModule() left the mapped metadata entry")
+	}
+}
+
+func TestDefaultPathMapper(t *testing.T) {
+	tests := []struct {
+		module, version, file string
+		want                  string
+	}{
+		{"rules_go", "", "metadata.json", "modules/rules_go/metadata.json"},
+		{"rules_go", "0.50.1", "source.json", "modules/rules_go/0.50.1/source.json"},
+		{"", "", "index.json", "modules/index.json"},
+	}
+	for _, tt := range tests {
+		if got := DefaultPathMapper(tt.module, tt.version, tt.file); got != tt.want {
+			t.Errorf("DefaultPathMapper(%q, %q, %q) = %q, want %q", tt.module, tt.version, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestLatestCompatible(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"modules/protobuf/metadata.json": `{
+			"versions": ["3.19.0", "3.20.0", "21.0", "22.0", "23.0"],
+			"yanked_versions": {"3.20.0": "broken"}
+		}`,
+		"modules/protobuf/3.19.0/MODULE.bazel": `module(name = "protobuf", version = "3.19.0")`,
+		"modules/protobuf/3.20.0/MODULE.bazel": `module(name = "protobuf", version = "3.20.0")`,
+		"modules/protobuf/21.0/MODULE.bazel":   `module(name = "protobuf", version = "21.0", compatibility_level = 1)`,
+		"modules/protobuf/22.0/MODULE.bazel":   `module(name = "protobuf", version = "22.0", compatibility_level = 1)`,
+		"modules/protobuf/23.0/MODULE.bazel":   `module(name = "protobuf", version = "23.0", compatibility_level = 2)`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	var fetched []string
+	fileServer := http.FileServer(http.Dir(dir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/MODULE.bazel") {
+			mu.Lock()
+			fetched = append(fetched, strings.Split(r.URL.Path, "/")[3])
+			mu.Unlock()
+		}
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	tests := []struct {
+		level       int
+		want        string
+		wantFetched []string
+	}{
+		{2, "23.0", []string{"23.0"}},
+		{1, "22.0", []string{"23.0", "22.0"}},
+		// 3.20.0 is yanked, so its MODULE.bazel is never fetched.
+		{0, "3.19.0", []string{"23.0", "22.0", "21.0", "3.19.0"}},
+	}
+	for _, tt := range tests {
+		fetched = nil
+		got, err := c.LatestCompatible(ctx, "protobuf", tt.level)
+		if err != nil || got != tt.want {
+			t.Errorf("LatestCompatible(%d) = %q, %v; want %q", tt.level, got, err, tt.want)
+		}
+		if !slices.Equal(fetched, tt.wantFetched) {
+			t.Errorf("LatestCompatible(%d) fetched %v, want %v", tt.level, fetched, tt.wantFetched)
+		}
+	}
+
+	if _, err := c.LatestCompatible(ctx, "protobuf", 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LatestCompatible(5) error = %v, want ErrNotFound", err)
+	}
+	if _, err := c.LatestCompatible(ctx, "missing", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("error for missing module = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSafeUpdate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"modules/protobuf/metadata.json": `{
+			"versions": ["3.19.0", "21.0", "21.1", "22.0", "22.1", "23.0"],
+			"yanked_versions": {"22.1": "broken"}
+		}`,
+		"modules/protobuf/3.19.0/MODULE.bazel": `module(name = "protobuf", version = "3.19.0")`,
+		"modules/protobuf/21.0/MODULE.bazel":   `module(name = "protobuf", version = "21.0", compatibility_level = 1)`,
+		"modules/protobuf/21.1/MODULE.bazel":   `module(name = "protobuf", version = "21.1", compatibility_level = 1)`,
+		"modules/protobuf/22.0/MODULE.bazel":   `module(name = "protobuf", version = "22.0", compatibility_level = 1)`,
+		"modules/protobuf/22.1/MODULE.bazel":   `module(name = "protobuf", version = "22.1", compatibility_level = 1)`,
+		"modules/protobuf/23.0/MODULE.bazel":   `module(name = "protobuf", version = "23.0", compatibility_level = 2)`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	fileServer := http.FileServer(http.Dir(dir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/MODULE.bazel") {
+			mu.Lock()
+			fetched[strings.Split(r.URL.Path, "/")[3]]++
+			mu.Unlock()
+		}
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithCache(NewMemoryCache()))
+	ctx := context.Background()
+
+	tests := []struct {
+		current   string
+		want      string
+		wantFound bool
+	}{
+		// 22.1 is yanked and 23.0 is at another level.
+		{"21.0", "22.0", true},
+		{"21.1", "22.0", true},
+		{"22.0", "", false},
+		{"23.0", "", false},
+		{"3.19.0", "", false},
+	}
+	for _, tt := range tests {
+		got, found, err := c.SafeUpdate(ctx, "protobuf", tt.current)
+		if err != nil || got != tt.want || found != tt.wantFound {
+			t.Errorf("SafeUpdate(%q) = %q, %v, %v; want %q, %v", tt.current, got, found, err, tt.want, tt.wantFound)
+		}
+	}
+	if fetched["22.1"] != 0 {
+		t.Errorf("yanked 22.1 MODULE.bazel fetched %d times, want 0", fetched["22.1"])
+	}
+	for v, n := range fetched {
+		if n != 1 {
+			t.Errorf("MODULE.bazel of %s fetched %d times, want once thanks to the cache", v, n)
+		}
+	}
+
+	if _, _, err := c.SafeUpdate(ctx, "protobuf", "1.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SafeUpdate() for missing version error = %v, want ErrNotFound", err)
+	}
+	if _, _, err := c.SafeUpdate(ctx, "missing", "1.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SafeUpdate() for missing module error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGzipResponses(t *testing.T) {
+	const body = `{"versions":["1.0.0","2.0.0"]}`
+	var acceptEncoding atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding.Store(r.Header.Get("Accept-Encoding"))
+		if r.URL.Path != "/modules/testmod/metadata.json" {
+			http.NotFound(w, r)
+			return
+		}
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(body))
+		zw.Close()
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	// A transport that never decompresses, so the client must.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	cache := NewMemoryCache()
+	c := New(WithBaseURL(srv.URL), WithCache(cache), WithTransport(transport))
+
+	meta, err := c.Metadata(context.Background(), "testmod")
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if !slices.Equal(meta.Versions, []string{"1.0.0", "2.0.0"}) {
+		t.Errorf("Versions = %v", meta.Versions)
+	}
+	if got := acceptEncoding.Load(); got != "gzip" {
+		t.Errorf("Accept-Encoding = %q, want gzip", got)
+	}
+	cached, ok := cache.Get("modules/testmod/metadata.json", 0)
+	if !ok || string(cached) != body {
+		t.Errorf("cached %q, want decompressed %q", cached, body)
+	}
+}
+
+func TestSourceStrict(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{
+				Versions:       []string{"1.0.0", "1.1.0"},
+				YankedVersions: map[string]string{"1.0.0": "CVE-2024-0001"},
+			})
+		case "/modules/testmod/1.0.0/source.json", "/modules/testmod/1.1.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "https://example.com/archive.zip"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("not yanked", func(t *testing.T) {
+		src, err := c.SourceStrict(ctx, "testmod", "1.1.0")
+		if err != nil || src.URL != "https://example.com/archive.zip" {
+			t.Errorf("SourceStrict() = %+v, %v", src, err)
+		}
+	})
+
+	t.Run("yanked", func(t *testing.T) {
+		src, err := c.SourceStrict(ctx, "testmod", "1.0.0")
+		if src != nil {
+			t.Errorf("SourceStrict() returned source %+v for a yanked version", src)
+		}
+		if !errors.Is(err, ErrYanked) {
+			t.Fatalf("error = %v, want ErrYanked", err)
+		}
+		var yanked *YankedError
+		if !errors.As(err, &yanked) || yanked.Version != "1.0.0" || yanked.Reason != "CVE-2024-0001" {
+			t.Errorf("error = %#v, want *YankedError with reason", err)
+		}
+		if errors.Is(err, ErrNotFound) {
+			t.Error("yanked error should not match ErrNotFound")
+		}
+
+		// Source itself is unchanged.
+		if _, err := c.Source(ctx, "testmod", "1.0.0"); err != nil {
+			t.Errorf("Source() error = %v", err)
+		}
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		if _, err := c.SourceStrict(ctx, "testmod", "9.9.9"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestResolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/metadata.json":
+			w.Write([]byte(`{"versions":["1.0.0","1.1.0"]}`))
+		case "/modules/testmod/1.0.0/source.json", "/modules/testmod/1.1.0/source.json":
+			w.Write([]byte(`{"url":"https://example.com/archive.zip","integrity":["sha256-AAAA","sha512-BBBB"],"strip_prefix":"testmod"}`))
+		case "/modules/testmod/1.0.0/MODULE.bazel", "/modules/testmod/1.1.0/MODULE.bazel":
+			w.Write([]byte(`module(name = "testmod")
+bazel_dep(name = "zlib", version = "1.3.1")
+bazel_dep(name = "rules_cc", version = "0.0.9")
+bazel_dep(name = "googletest", version = "1.14.0", dev_dependency = True)
+bazel_dep(name = "abseil-cpp")
+`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	resolved, err := c.Resolve(ctx, "testmod", "1.0.0")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if resolved.Name != "testmod" || resolved.Version != "1.0.0" || resolved.Source.StripPrefix != "testmod" {
+		t.Errorf("Resolve() = %+v", resolved)
+	}
+	wantDeps := map[string]string{"zlib": "1.3.1", "rules_cc": "0.0.9", "abseil-cpp": ""}
+	if !maps.Equal(resolved.Deps, wantDeps) {
+		t.Errorf("Deps = %v, want %v", resolved.Deps, wantDeps)
+	}
+
+	t.Run("json round trip", func(t *testing.T) {
+		data, err := json.Marshal(resolved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const want = `{"name":"testmod","version":"1.0.0",` +
+			`"source":{"url":"https://example.com/archive.zip","strip_prefix":"testmod","integrity":["sha256-AAAA","sha512-BBBB"]},` +
+			`"deps":{"abseil-cpp":"","rules_cc":"0.0.9","zlib":"1.3.1"}}`
+		if string(data) != want {
+			t.Errorf("json.Marshal() =\n%s\nwant\n%s", data, want)
+		}
+
+		var decoded ResolvedModule
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(decoded.Deps, resolved.Deps) || !slices.Equal(decoded.Source.Integrities, resolved.Source.Integrities) {
+			t.Errorf("decoded = %+v, want %+v", decoded, resolved)
+		}
+		again, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("re-encoded =\n%s\nwant\n%s", again, data)
+		}
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		resolved, err := c.Resolve(ctx, "testmod", "")
+		if err != nil || resolved.Version != "1.1.0" {
+			t.Errorf("Resolve() = %+v, %v; want version 1.1.0", resolved, err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, tt := range []struct{ module, version string }{{"testmod", "9.9.9"}, {"missing", "1.0.0"}} {
+			if _, err := c.Resolve(ctx, tt.module, tt.version); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Resolve(%q, %q) error = %v, want ErrNotFound", tt.module, tt.version, err)
+			}
+		}
+	})
+}
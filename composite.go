@@ -0,0 +1,88 @@
+package bcr
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// CompositeRegistry is a Registry that tries an ordered list of
+// registries in turn, such as an internal mirror backed by the public
+// BCR.
+//
+// Each lookup returns the first successful result. A backend that reports
+// [ErrNotFound] falls through to the next one; any other error, such as a
+// failed request, is returned immediately so that an outage of one
+// backend is not masked by another.
+type CompositeRegistry struct {
+	regs []Registry
+}
+
+// NewCompositeRegistry creates a registry that tries regs in order.
+func NewCompositeRegistry(regs ...Registry) *CompositeRegistry {
+	return &CompositeRegistry{regs: regs}
+}
+
+// Metadata fetches module metadata from the first registry that has the
+// module.
+func (r *CompositeRegistry) Metadata(ctx context.Context, module string) (*Metadata, error) {
+	return firstFound(r, &NotFoundError{Module: module}, func(reg Registry) (*Metadata, error) {
+		return reg.Metadata(ctx, module)
+	})
+}
+
+// Source fetches source information from the first registry that has the
+// module version.
+func (r *CompositeRegistry) Source(ctx context.Context, module, version string) (*Source, error) {
+	return firstFound(r, &NotFoundError{Module: module, Version: version}, func(reg Registry) (*Source, error) {
+		return reg.Source(ctx, module, version)
+	})
+}
+
+// ModuleFile fetches the MODULE.bazel content from the first registry that
+// has the module version.
+func (r *CompositeRegistry) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	return firstFound(r, &NotFoundError{Module: module, Version: version}, func(reg Registry) ([]byte, error) {
+		return reg.ModuleFile(ctx, module, version)
+	})
+}
+
+// firstFound calls fn for each registry of r in order until one succeeds
+// or fails with an error other than not-found. If every registry misses,
+// the last not-found error is returned, or notFound if r is empty.
+func firstFound[T any](r *CompositeRegistry, notFound error, fn func(Registry) (T, error)) (T, error) {
+	var zero T
+	err := notFound
+	for _, reg := range r.regs {
+		var v T
+		v, err = fn(reg)
+		if err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return zero, err
+		}
+	}
+	return zero, err
+}
+
+// String returns the registries tried, in order.
+func (r *CompositeRegistry) String() string {
+	names := make([]string, len(r.regs))
+	for i, reg := range r.regs {
+		if s, ok := reg.(interface{ String() string }); ok {
+			names[i] = s.String()
+		} else {
+			names[i] = "?"
+		}
+	}
+	return "composite(" + strings.Join(names, ", ") + ")"
+}
+
+// Type returns the registry type ("composite").
+func (r *CompositeRegistry) Type() string {
+	return "composite"
+}
+
+// Ensure CompositeRegistry implements Registry at compile time.
+var _ Registry = (*CompositeRegistry)(nil)
@@ -0,0 +1,151 @@
+package bcr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stubRegistry is a Registry that returns fixed results and counts calls.
+type stubRegistry struct {
+	meta  *Metadata
+	src   *Source
+	file  []byte
+	err   error
+	calls int
+}
+
+func (s *stubRegistry) Metadata(ctx context.Context, module string) (*Metadata, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.meta, nil
+}
+
+func (s *stubRegistry) Source(ctx context.Context, module, version string) (*Source, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.src, nil
+}
+
+func (s *stubRegistry) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.file, nil
+}
+
+func TestCompositeRegistry(t *testing.T) {
+	ctx := context.Background()
+	missing := func() *stubRegistry { return &stubRegistry{err: &NotFoundError{Module: "testmod"}} }
+	found := func() *stubRegistry {
+		return &stubRegistry{
+			meta: &Metadata{Versions: []string{"1.0.0"}},
+			src:  &Source{URL: "https://example.com/testmod.tar.gz"},
+			file: []byte(`module(name = "testmod")`),
+		}
+	}
+
+	t.Run("falls through not found", func(t *testing.T) {
+		mirror, public := missing(), found()
+		reg := NewCompositeRegistry(mirror, public)
+
+		meta, err := reg.Metadata(ctx, "testmod")
+		if err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if meta.Latest() != "1.0.0" {
+			t.Errorf("Latest() = %q, want %q", meta.Latest(), "1.0.0")
+		}
+		if src, err := reg.Source(ctx, "testmod", "1.0.0"); err != nil || src.URL == "" {
+			t.Errorf("Source() = %v, %v", src, err)
+		}
+		if file, err := reg.ModuleFile(ctx, "testmod", "1.0.0"); err != nil || len(file) == 0 {
+			t.Errorf("ModuleFile() = %q, %v", file, err)
+		}
+		if mirror.calls != 3 || public.calls != 3 {
+			t.Errorf("calls = %d, %d, want 3, 3", mirror.calls, public.calls)
+		}
+	})
+
+	t.Run("first hit wins", func(t *testing.T) {
+		mirror, public := found(), found()
+		reg := NewCompositeRegistry(mirror, public)
+		if _, err := reg.Metadata(ctx, "testmod"); err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if public.calls != 0 {
+			t.Errorf("second registry called %d times, want 0", public.calls)
+		}
+	})
+
+	t.Run("all miss", func(t *testing.T) {
+		reg := NewCompositeRegistry(missing(), missing())
+		_, err := reg.Metadata(ctx, "testmod")
+		var notFound *NotFoundError
+		if !errors.As(err, &notFound) {
+			t.Errorf("error = %v, want *NotFoundError", err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := NewCompositeRegistry().Source(ctx, "testmod", "1.0.0")
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("aborts on other errors", func(t *testing.T) {
+		outage := &stubRegistry{err: &RequestError{URL: "https://mirror.example.com", StatusCode: http.StatusInternalServerError}}
+		public := found()
+		reg := NewCompositeRegistry(outage, public)
+
+		_, err := reg.ModuleFile(ctx, "testmod", "1.0.0")
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("error = %v, want *RequestError with status 500", err)
+		}
+		if public.calls != 0 {
+			t.Errorf("fallback registry called %d times after an outage, want 0", public.calls)
+		}
+	})
+
+	t.Run("with file registry", func(t *testing.T) {
+		root := t.TempDir()
+		dir := filepath.Join(root, "modules", "local")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte(`{"versions": ["0.1.0"]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		reg := NewCompositeRegistry(NewFileRegistry(root), found())
+		meta, err := reg.Metadata(ctx, "local")
+		if err != nil || meta.Latest() != "0.1.0" {
+			t.Errorf("Metadata(local) = %v, %v, want version 0.1.0", meta, err)
+		}
+		meta, err = reg.Metadata(ctx, "testmod")
+		if err != nil || meta.Latest() != "1.0.0" {
+			t.Errorf("Metadata(testmod) = %v, %v, want version 1.0.0", meta, err)
+		}
+	})
+}
+
+func TestCompositeRegistryString(t *testing.T) {
+	reg := NewCompositeRegistry(NewFileRegistry("/srv/registry"), New())
+	want := "composite(file:///srv/registry, " + DefaultBaseURL + ")"
+	if got := reg.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := reg.Type(); got != "composite" {
+		t.Errorf("Type() = %q, want %q", got, "composite")
+	}
+}
@@ -0,0 +1,132 @@
+package bcr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versionBound is a single comparison in a version constraint, such as
+// ">=1.0.0".
+type versionBound struct {
+	op      string // one of "=", "!=", ">", ">=", "<", "<="
+	version string
+}
+
+// matches reports whether v satisfies the bound.
+func (b versionBound) matches(v string) bool {
+	c := CompareVersions(v, b.version)
+	switch b.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	default: // "<="
+		return c <= 0
+	}
+}
+
+// constraintOps lists the operators recognized by parseConstraint,
+// longest first so that ">=" is not read as ">".
+var constraintOps = []string{">=", "<=", "!=", "==", ">", "<", "=", "~", "^"}
+
+// parseConstraint parses a version constraint into the bounds a version
+// must all satisfy. See [Metadata.LatestMatching] for the grammar.
+func parseConstraint(constraint string) ([]versionBound, error) {
+	fields := strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("bcr: invalid version constraint %q: empty", constraint)
+	}
+
+	var bounds []versionBound
+	for i := 0; i < len(fields); i++ {
+		term := fields[i]
+		if term == "*" {
+			continue
+		}
+
+		op := ""
+		for _, candidate := range constraintOps {
+			if strings.HasPrefix(term, candidate) {
+				op = candidate
+				break
+			}
+		}
+		version := strings.TrimPrefix(term, op)
+		// Allow whitespace between an operator and its version.
+		if version == "" && op != "" && i+1 < len(fields) {
+			i++
+			version = fields[i]
+		}
+		if _, ok := parseVersion(version); !ok {
+			return nil, fmt.Errorf("bcr: invalid version constraint %q: malformed version %q", constraint, version)
+		}
+
+		switch op {
+		case "", "=", "==":
+			bounds = append(bounds, versionBound{"=", version})
+		case "~", "^":
+			upper, err := rangeUpperBound(op, version)
+			if err != nil {
+				return nil, fmt.Errorf("bcr: invalid version constraint %q: %w", constraint, err)
+			}
+			bounds = append(bounds, versionBound{">=", version}, versionBound{"<", upper})
+		default:
+			// <2.0.0 also excludes prereleases such as 2.0.0-rc1.
+			if v, _ := parseVersion(version); op == "<" && v.prerelease == nil {
+				version, _, _ = strings.Cut(version, "+")
+				version += "-0"
+			}
+			bounds = append(bounds, versionBound{op, version})
+		}
+	}
+	return bounds, nil
+}
+
+// rangeUpperBound returns the exclusive upper bound of a tilde or caret
+// range starting at version. The bound is the lowest prerelease of the
+// next release, so that prereleases of that release are excluded too.
+func rangeUpperBound(op, version string) (string, error) {
+	v, _ := parseVersion(version)
+	for _, id := range v.release {
+		if !isNumeric(id) {
+			return "", fmt.Errorf("%s range needs numeric release identifiers, got %q", op, version)
+		}
+	}
+
+	// Number of leading release identifiers kept; the last one is bumped.
+	keep := 1
+	switch op {
+	case "~":
+		// ~1.4.2 and ~1.4 allow patch changes; ~1 allows minor changes.
+		if len(v.release) > 1 {
+			keep = 2
+		}
+	case "^":
+		// ^1.2.3 allows minor changes; ^0.2.3 patch changes; ^0.0.3 none.
+		for keep < len(v.release) && v.release[keep-1] == "0" {
+			keep++
+		}
+	}
+
+	parts := make([]string, keep)
+	for i := range keep {
+		n, err := strconv.ParseUint(v.release[i], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s range version %q is out of range", op, version)
+		}
+		if i == keep-1 {
+			n++
+		}
+		parts[i] = strconv.FormatUint(n, 10)
+	}
+	return strings.Join(parts, ".") + "-0", nil
+}
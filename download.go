@@ -0,0 +1,246 @@
+package bcr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadItem is a module version to download with [Client.DownloadMany].
+type DownloadItem struct {
+	// Ref identifies the module version.
+	Ref ModuleRef
+
+	// Source is the version's source information, as returned by
+	// [Client.Source].
+	Source *Source
+}
+
+// DownloadMany downloads the source archives of items concurrently into
+// destDir, verifying each against its integrity. At most as many archives
+// as set with [WithMaxConcurrency] are downloaded at once.
+//
+// Each archive is written to destDir/<module>/<version>/<filename>, where
+// filename is given by [Source.Filename]. Files are only moved into place
+// once fully downloaded and verified, so a failed or tampered download
+// leaves nothing behind. Mirrors are tried in turn as with
+// [Client.DownloadSource]. Only archive sources with an integrity can be
+// downloaded.
+//
+// The returned map holds an error for each item that failed, keyed by
+// its Ref; it is empty if all downloads succeeded. If ctx is cancelled,
+// items not yet started fail with the context's error.
+func (c *Client) DownloadMany(ctx context.Context, items []DownloadItem, destDir string) map[ModuleRef]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = make(map[ModuleRef]error)
+		sem  = make(chan struct{}, c.maxConcurrency)
+	)
+	fail := func(ref ModuleRef, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs[ref] = err
+	}
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			fail(item.Ref, err)
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			fail(item.Ref, ctx.Err())
+			continue
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			if err := c.downloadItem(ctx, item, destDir); err != nil {
+				fail(item.Ref, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// downloadItem downloads and verifies a single item. See [Client.DownloadMany].
+func (c *Client) downloadItem(ctx context.Context, item DownloadItem, destDir string) error {
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceDownload)
+	defer cancel()
+
+	ref, src := item.Ref, item.Source
+	integrities, err := c.archiveIntegrities(ref, src)
+	if err != nil {
+		return err
+	}
+	if !isPathSegment(ref.Name) || !isPathSegment(ref.Version) {
+		return fmt.Errorf("bcr: invalid module reference %q", ref)
+	}
+	name := src.Filename()
+	if name == "" {
+		return fmt.Errorf("bcr: cannot derive archive filename for %s", ref)
+	}
+
+	// Stage the download in destDir, so that the version directory is only
+	// created once the archive has been verified.
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return err
+	}
+	body, err := c.openArchive(ctx, ref, src.AllURLs(), integrities)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return writeReaderAtomic(destDir, filepath.Join(destDir, ref.Name, ref.Version, name), body, 0o644)
+}
+
+// DownloadSource downloads the source archive of a module version and
+// streams it to w, verifying it against the integrity in source.json.
+// It returns the version's source information.
+//
+// Each of [Source.AllURLs] is tried in turn until one responds
+// successfully. The archive is written to w as it arrives, so on error w
+// may hold partial or unverified content, which the caller must discard.
+// A mismatching archive is reported as an [*IntegrityError]. Only archive
+// sources can be downloaded.
+func (c *Client) DownloadSource(ctx context.Context, module, version string, w io.Writer) (*Source, error) {
+	ctx, cancel := c.withOperationTimeout(ctx, ResourceDownload)
+	defer cancel()
+
+	src, err := c.Source(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	ref := ModuleRef{Name: module, Version: version}
+	integrities, err := c.archiveIntegrities(ref, src)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.openArchive(ctx, ref, src.AllURLs(), integrities)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if _, err := io.Copy(w, body); err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+// archiveIntegrities checks that src is a downloadable archive source
+// allowed by the client's download policy, and returns the integrities
+// its archive may match.
+func (c *Client) archiveIntegrities(ref ModuleRef, src *Source) ([]string, error) {
+	if src == nil {
+		return nil, fmt.Errorf("bcr: no source for %s", ref)
+	}
+	if t := src.SourceType(); c.allowedSourceTypes != nil && !c.allowedSourceTypes[t] {
+		return nil, &SourceTypeNotAllowedError{Module: ref.Name, Version: ref.Version, Type: t}
+	}
+	if host, rejected := src.rejectedHost(c.allowedHosts); c.allowedHosts != nil && rejected {
+		return nil, &HostNotAllowedError{Module: ref.Name, Version: ref.Version, Host: host}
+	}
+	if t := src.SourceType(); t != "archive" {
+		return nil, fmt.Errorf("bcr: cannot download %s source for %s", t, ref)
+	}
+	if len(src.AllURLs()) == 0 {
+		return nil, fmt.Errorf("bcr: source for %s has no URL", ref)
+	}
+	integrities := sourceIntegrities(src)
+	if len(integrities) == 0 {
+		return nil, fmt.Errorf("bcr: source for %s has no integrity", ref)
+	}
+	return integrities, nil
+}
+
+// openArchive starts downloading an archive of ref from the first of urls
+// that responds successfully; if none does, their errors are joined.
+// Reading the returned body to EOF fails with an [*IntegrityError] unless
+// the archive matches one of integrities.
+func (c *Client) openArchive(ctx context.Context, ref ModuleRef, urls []string, integrities []string) (io.ReadCloser, error) {
+	var (
+		resp *http.Response
+		errs []error
+	)
+	for _, rawURL := range urls {
+		var err error
+		if resp, err = c.getURL(ctx, ref, rawURL); err == nil {
+			break
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if resp == nil {
+		return nil, errors.Join(errs...)
+	}
+
+	r, err := newVerifyingReader(resp.Body, integrities)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, resp.Body}, nil
+}
+
+// getURL makes a GET request for an absolute URL outside the registry,
+// such as a source archive of ref, after applying
+// [WithDownloadURLRewriter]. Registry query parameters are not sent.
+// Errors, logs and metric events report rawURL, not the rewritten URL.
+func (c *Client) getURL(ctx context.Context, ref ModuleRef, rawURL string) (resp *http.Response, err error) {
+	start := time.Now()
+	defer func() {
+		status := responseStatus(resp, err)
+		c.logRequest(ctx, http.MethodGet, rawURL, ref.Name, ref.Version, status, time.Since(start), err)
+		c.observe(rawURL, start, &status, &err)
+	}()
+
+	fetchURL := rawURL
+	if c.rewriteURL != nil {
+		fetchURL = c.rewriteURL(rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fetchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("bcr: failed to create request for %s: %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err = c.archiveHTTP.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = rawURL
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, &RequestError{URL: rawURL, Err: ctxErr}
+		}
+		return nil, &RequestError{URL: rawURL, Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &RequestError{URL: rawURL, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// isPathSegment reports whether s can be used as a single path element
+// without escaping its parent directory.
+func isPathSegment(s string) bool {
+	return s != "" && s != "." && s != ".." && !strings.ContainsAny(s, `/\`)
+}
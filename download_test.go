@@ -0,0 +1,555 @@
+package bcr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadMany(t *testing.T) {
+	archives := map[string][]byte{
+		"/rules_go-v0.50.1.zip":  []byte("rules_go archive"),
+		"/protobuf-29.0.tar.gz":  []byte("protobuf archive"),
+		"/tampered-1.0.0.tar.gz": []byte("tampered archive"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := archives[r.URL.Path]; ok {
+			w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	rulesGo := ModuleRef{Name: "rules_go", Version: "0.50.1"}
+	protobuf := ModuleRef{Name: "protobuf", Version: "29.0"}
+	tampered := ModuleRef{Name: "tampered", Version: "1.0.0"}
+	missing := ModuleRef{Name: "missing", Version: "1.0.0"}
+	gitRepo := ModuleRef{Name: "gitmod", Version: "1.0.0"}
+	escaping := ModuleRef{Name: "..", Version: "1.0.0"}
+
+	items := []DownloadItem{
+		{rulesGo, &Source{
+			URL:       srv.URL + "/rules_go-v0.50.1.zip",
+			Integrity: sha256Integrity(archives["/rules_go-v0.50.1.zip"]),
+		}},
+		{protobuf, &Source{
+			URL: srv.URL + "/protobuf-29.0.tar.gz?raw=1",
+			// Any listed integrity may match.
+			Integrities: []string{sha256Integrity([]byte("other")), sha256Integrity(archives["/protobuf-29.0.tar.gz"])},
+		}},
+		{tampered, &Source{
+			URL:       srv.URL + "/tampered-1.0.0.tar.gz",
+			Integrity: sha256Integrity([]byte("original archive")),
+		}},
+		{missing, &Source{
+			URL:       srv.URL + "/missing-1.0.0.tar.gz",
+			Integrity: sha256Integrity(nil),
+		}},
+		{gitRepo, &Source{Type: "git_repository", Remote: "https://github.com/owner/repo.git"}},
+		{escaping, &Source{
+			URL:       srv.URL + "/rules_go-v0.50.1.zip",
+			Integrity: sha256Integrity(archives["/rules_go-v0.50.1.zip"]),
+		}},
+	}
+
+	dest := t.TempDir()
+	c := New(WithBaseURL(srv.URL))
+	errs := c.DownloadMany(context.Background(), items, dest)
+
+	for _, ok := range []ModuleRef{rulesGo, protobuf} {
+		if err := errs[ok]; err != nil {
+			t.Errorf("%s: error = %v", ok, err)
+		}
+	}
+
+	got, err := os.ReadFile(filepath.Join(dest, "rules_go", "0.50.1", "rules_go-v0.50.1.zip"))
+	if err != nil || string(got) != "rules_go archive" {
+		t.Errorf("rules_go archive = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "protobuf", "29.0", "protobuf-29.0.tar.gz")); err != nil {
+		t.Errorf("protobuf archive not written: %v", err)
+	}
+
+	if !errors.Is(errs[tampered], ErrIntegrityMismatch) {
+		t.Errorf("tampered: error = %v, want ErrIntegrityMismatch", errs[tampered])
+	}
+	for _, failed := range []ModuleRef{tampered, missing, gitRepo} {
+		if _, err := os.Stat(filepath.Join(dest, failed.Name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: failed download left a directory behind", failed)
+		}
+	}
+	entries, _ := os.ReadDir(dest)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+
+	var reqErr *RequestError
+	if !errors.As(errs[missing], &reqErr) || reqErr.StatusCode != http.StatusNotFound {
+		t.Errorf("missing: error = %v, want *RequestError with status 404", errs[missing])
+	}
+	if errs[gitRepo] == nil {
+		t.Error("git_repository source should fail")
+	}
+	if errs[escaping] == nil {
+		t.Error("module name escaping destDir should fail")
+	}
+	if len(errs) != 4 {
+		t.Errorf("got %d errors, want 4: %v", len(errs), errs)
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		errs := c.DownloadMany(ctx, items[:1], t.TempDir())
+		if !errors.Is(errs[rulesGo], context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", errs[rulesGo])
+		}
+	})
+}
+
+func TestDownloadSource(t *testing.T) {
+	archive := []byte("rules_go archive")
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/rules_go/0.50.1/source.json":
+			json.NewEncoder(w).Encode(&Source{
+				URL:       srvURL + "/rules_go-v0.50.1.zip",
+				Integrity: sha256Integrity(archive),
+			})
+		case "/modules/tampered/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{
+				URL:       srvURL + "/rules_go-v0.50.1.zip",
+				Integrity: sha256Integrity([]byte("original archive")),
+			})
+		case "/modules/gitmod/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{Type: "git_repository", Remote: "https://github.com/owner/repo.git"})
+		case "/rules_go-v0.50.1.zip":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+
+	t.Run("valid", func(t *testing.T) {
+		var buf bytes.Buffer
+		src, err := c.DownloadSource(ctx, "rules_go", "0.50.1", &buf)
+		if err != nil {
+			t.Fatalf("DownloadSource() error = %v", err)
+		}
+		if src.Integrity != sha256Integrity(archive) {
+			t.Errorf("Integrity = %q", src.Integrity)
+		}
+		if buf.String() != string(archive) {
+			t.Errorf("archive = %q, want %q", buf.String(), archive)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		_, err := c.DownloadSource(ctx, "tampered", "1.0.0", io.Discard)
+		var intErr *IntegrityError
+		if !errors.As(err, &intErr) {
+			t.Fatalf("error = %v, want *IntegrityError", err)
+		}
+		if intErr.Expected != sha256Integrity([]byte("original archive")) {
+			t.Errorf("Expected = %q", intErr.Expected)
+		}
+		if intErr.Actual != sha256Integrity(archive) {
+			t.Errorf("Actual = %q, want %q", intErr.Actual, sha256Integrity(archive))
+		}
+	})
+
+	t.Run("not an archive", func(t *testing.T) {
+		if _, err := c.DownloadSource(ctx, "gitmod", "1.0.0", io.Discard); err == nil {
+			t.Error("git_repository source should fail")
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := c.DownloadSource(ctx, "missing", "1.0.0", io.Discard); !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDownloadMirrors(t *testing.T) {
+	archive := []byte("zlib archive")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down/zlib-1.3.1.tar.gz":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/up/zlib-1.3.1.tar.gz":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL))
+	ctx := context.Background()
+	ref := ModuleRef{Name: "zlib", Version: "1.3.1"}
+
+	t.Run("falls back to next mirror", func(t *testing.T) {
+		src := &Source{
+			URLs:      []string{srv.URL + "/down/zlib-1.3.1.tar.gz", srv.URL + "/missing/zlib-1.3.1.tar.gz", srv.URL + "/up/zlib-1.3.1.tar.gz"},
+			Integrity: sha256Integrity(archive),
+		}
+		dest := t.TempDir()
+		if errs := c.DownloadMany(ctx, []DownloadItem{{ref, src}}, dest); len(errs) != 0 {
+			t.Fatalf("DownloadMany() errors = %v", errs)
+		}
+		got, err := os.ReadFile(filepath.Join(dest, "zlib", "1.3.1", "zlib-1.3.1.tar.gz"))
+		if err != nil || string(got) != string(archive) {
+			t.Errorf("archive = %q, %v", got, err)
+		}
+	})
+
+	t.Run("all mirrors fail", func(t *testing.T) {
+		src := &Source{
+			URLs:      []string{srv.URL + "/down/zlib-1.3.1.tar.gz", srv.URL + "/missing/zlib-1.3.1.tar.gz"},
+			Integrity: sha256Integrity(archive),
+		}
+		err := c.DownloadMany(ctx, []DownloadItem{{ref, src}}, t.TempDir())[ref]
+		for _, status := range []int{http.StatusServiceUnavailable, http.StatusNotFound} {
+			if !strings.Contains(err.Error(), fmt.Sprint(status)) {
+				t.Errorf("error = %v, want it to report status %d", err, status)
+			}
+		}
+	})
+}
+
+func TestDownloadTimeout(t *testing.T) {
+	archive := []byte("slow archive")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/slow/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: "http://" + r.Host + "/slow-1.0.0.tar.gz", Integrity: sha256Integrity(archive)})
+		case "/slow-1.0.0.tar.gz":
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// The download timeout replaces the short WithTimeout for downloads
+	// only.
+	c := New(WithBaseURL(srv.URL), WithTimeout(50*time.Millisecond), WithOperationTimeout(ResourceDownload, 20*time.Millisecond))
+	ctx := context.Background()
+
+	t.Run("applies to DownloadSource", func(t *testing.T) {
+		_, err := c.DownloadSource(ctx, "slow", "1.0.0", io.Discard)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("applies to DownloadMany", func(t *testing.T) {
+		ref := ModuleRef{Name: "slow", Version: "1.0.0"}
+		items := []DownloadItem{{ref, &Source{URL: srv.URL + "/slow-1.0.0.tar.gz", Integrity: sha256Integrity(archive)}}}
+		errs := c.DownloadMany(ctx, items, t.TempDir())
+		if !errors.Is(errs[ref], context.DeadlineExceeded) {
+			t.Errorf("error = %v, want context.DeadlineExceeded", errs[ref])
+		}
+	})
+
+	t.Run("caller deadline wins", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		var buf bytes.Buffer
+		if _, err := c.DownloadSource(ctx, "slow", "1.0.0", &buf); err != nil {
+			t.Fatalf("DownloadSource() error = %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), archive) {
+			t.Errorf("downloaded %q, want %q", buf.Bytes(), archive)
+		}
+	})
+
+	t.Run("long download timeout", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithTimeout(20*time.Millisecond), WithOperationTimeout(ResourceDownload, 5*time.Second))
+		if _, err := c.DownloadSource(ctx, "slow", "1.0.0", io.Discard); err != nil {
+			t.Errorf("DownloadSource() error = %v", err)
+		}
+	})
+}
+
+func TestDownloadManyConcurrency(t *testing.T) {
+	archive := []byte("archive")
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	var items []DownloadItem
+	for i := range 8 {
+		items = append(items, DownloadItem{
+			Ref:    ModuleRef{Name: fmt.Sprintf("mod%d", i), Version: "1.0.0"},
+			Source: &Source{URL: srv.URL + "/archive.tar.gz", Integrity: sha256Integrity(archive)},
+		})
+	}
+
+	c := New(WithBaseURL(srv.URL), WithMaxConcurrency(2))
+	if errs := c.DownloadMany(context.Background(), items, t.TempDir()); len(errs) != 0 {
+		t.Fatalf("DownloadMany() errors = %v", errs)
+	}
+	if peak != 2 {
+		t.Errorf("peak concurrent downloads = %d, want 2", peak)
+	}
+}
+
+func TestAllowedSourceTypes(t *testing.T) {
+	archive := []byte("rules_go archive")
+	var archiveRequests atomic.Int32
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/rules_go/0.50.1/source.json":
+			json.NewEncoder(w).Encode(&Source{URL: srvURL + "/rules_go-v0.50.1.zip", Integrity: sha256Integrity(archive)})
+		case "/modules/gitmod/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{Type: "git_repository", Remote: "https://github.com/owner/repo.git"})
+		case "/rules_go-v0.50.1.zip":
+			archiveRequests.Add(1)
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+	ctx := context.Background()
+
+	t.Run("allowed type", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithAllowedSourceTypes("archive"))
+		if _, err := c.DownloadSource(ctx, "rules_go", "0.50.1", io.Discard); err != nil {
+			t.Errorf("DownloadSource() error = %v", err)
+		}
+
+		var typeErr *SourceTypeNotAllowedError
+		_, err := c.DownloadSource(ctx, "gitmod", "1.0.0", io.Discard)
+		if !errors.As(err, &typeErr) || typeErr.Type != "git_repository" || typeErr.Module != "gitmod" {
+			t.Errorf("DownloadSource(gitmod) error = %v, want *SourceTypeNotAllowedError for git_repository", err)
+		}
+		if !errors.Is(err, ErrDownloadNotAllowed) {
+			t.Errorf("DownloadSource(gitmod) error = %v, want ErrDownloadNotAllowed", err)
+		}
+	})
+
+	t.Run("rejected before download", func(t *testing.T) {
+		archiveRequests.Store(0)
+		c := New(WithBaseURL(srv.URL), WithAllowedSourceTypes("local_path"))
+		var typeErr *SourceTypeNotAllowedError
+		if _, err := c.DownloadSource(ctx, "rules_go", "0.50.1", io.Discard); !errors.As(err, &typeErr) || typeErr.Type != "archive" {
+			t.Errorf("DownloadSource() error = %v, want *SourceTypeNotAllowedError for archive", err)
+		}
+
+		ref := ModuleRef{Name: "rules_go", Version: "0.50.1"}
+		items := []DownloadItem{{ref, &Source{URL: srv.URL + "/rules_go-v0.50.1.zip", Integrity: sha256Integrity(archive)}}}
+		dest := t.TempDir()
+		if err := c.DownloadMany(ctx, items, dest)[ref]; !errors.As(err, &typeErr) {
+			t.Errorf("DownloadMany() error = %v, want *SourceTypeNotAllowedError", err)
+		}
+		if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+			t.Errorf("DownloadMany() created %v", entries)
+		}
+		if n := archiveRequests.Load(); n != 0 {
+			t.Errorf("archive requested %d times, want 0", n)
+		}
+	})
+}
+
+func TestDownloadURLRewriter(t *testing.T) {
+	archive := []byte("rules_go archive")
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("origin received request for %s, want it sent to the mirror", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	defer origin.Close()
+
+	var mirrored []string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrored = append(mirrored, r.URL.Path)
+		switch r.URL.Path {
+		case "/proxy/rules_go-v0.50.1.zip":
+			w.Write(archive)
+		case "/proxy/tampered-1.0.0.zip":
+			w.Write([]byte("tampered archive"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mirror.Close()
+
+	c := New(WithBaseURL(origin.URL), WithDownloadURLRewriter(func(u string) string {
+		return strings.Replace(u, origin.URL, mirror.URL+"/proxy", 1)
+	}))
+	ctx := context.Background()
+	download := func(file, integrity string) error {
+		ref := ModuleRef{Name: "rules_go", Version: "0.50.1"}
+		src := &Source{URL: origin.URL + "/" + file, Integrity: integrity}
+		return c.DownloadMany(ctx, []DownloadItem{{ref, src}}, t.TempDir())[ref]
+	}
+
+	if err := download("rules_go-v0.50.1.zip", sha256Integrity(archive)); err != nil {
+		t.Errorf("download error = %v", err)
+	}
+	if want := []string{"/proxy/rules_go-v0.50.1.zip"}; !slices.Equal(mirrored, want) {
+		t.Errorf("mirror received %v, want %v", mirrored, want)
+	}
+
+	if err := download("tampered-1.0.0.zip", sha256Integrity(archive)); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Errorf("tampered download error = %v, want ErrIntegrityMismatch", err)
+	}
+
+	var reqErr *RequestError
+	err := download("missing-1.0.0.zip", sha256Integrity(archive))
+	if !errors.As(err, &reqErr) || reqErr.URL != origin.URL+"/missing-1.0.0.zip" {
+		t.Errorf("missing download error = %v, want *RequestError for the original URL", err)
+	}
+}
+
+func TestAllowedDownloadHosts(t *testing.T) {
+	archive := []byte("rules_go archive")
+	var archiveRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/rules_go/0.50.1/source.json":
+			json.NewEncoder(w).Encode(&Source{
+				URLs:      []string{"http://" + r.Host + "/rules_go-v0.50.1.zip", "https://mirror.example.com/rules_go-v0.50.1.zip"},
+				Integrity: sha256Integrity(archive),
+			})
+		case "/rules_go-v0.50.1.zip":
+			archiveRequests.Add(1)
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	host, _, _ = strings.Cut(host, ":")
+	ctx := context.Background()
+
+	t.Run("allowed", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithAllowedDownloadHosts(host, "MIRROR.example.com"))
+		if _, err := c.DownloadSource(ctx, "rules_go", "0.50.1", io.Discard); err != nil {
+			t.Errorf("DownloadSource() error = %v", err)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		archiveRequests.Store(0)
+		c := New(WithBaseURL(srv.URL), WithAllowedDownloadHosts(host))
+		var hostErr *HostNotAllowedError
+		_, err := c.DownloadSource(ctx, "rules_go", "0.50.1", io.Discard)
+		if !errors.As(err, &hostErr) || hostErr.Host != "mirror.example.com" || hostErr.Module != "rules_go" {
+			t.Errorf("DownloadSource() error = %v, want *HostNotAllowedError for mirror.example.com", err)
+		}
+		if !errors.Is(err, ErrDownloadNotAllowed) {
+			t.Errorf("DownloadSource() error = %v, want ErrDownloadNotAllowed", err)
+		}
+
+		ref := ModuleRef{Name: "rules_go", Version: "0.50.1"}
+		items := []DownloadItem{{ref, &Source{URL: "https://evil.example.com/rules_go-v0.50.1.zip", Integrity: sha256Integrity(archive)}}}
+		dest := t.TempDir()
+		if err := c.DownloadMany(ctx, items, dest)[ref]; !errors.As(err, &hostErr) || hostErr.Host != "evil.example.com" {
+			t.Errorf("DownloadMany() error = %v, want *HostNotAllowedError for evil.example.com", err)
+		}
+		if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+			t.Errorf("DownloadMany() created %v", entries)
+		}
+		if n := archiveRequests.Load(); n != 0 {
+			t.Errorf("archive requested %d times, want 0", n)
+		}
+	})
+}
+
+func TestDownloadLogsAndMetrics(t *testing.T) {
+	archive := []byte("zlib archive")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/up/zlib-1.3.1.tar.gz" {
+			w.Write(archive)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	h := &recordingHandler{}
+	var events []MetricEvent
+	c := New(WithBaseURL(srv.URL), WithLogger(slog.New(h)), WithMetricsHook(func(e MetricEvent) {
+		events = append(events, e)
+	}))
+	ref := ModuleRef{Name: "zlib", Version: "1.3.1"}
+	src := &Source{
+		URLs:      []string{srv.URL + "/down/zlib-1.3.1.tar.gz", srv.URL + "/up/zlib-1.3.1.tar.gz"},
+		Integrity: sha256Integrity(archive),
+	}
+	if errs := c.DownloadMany(context.Background(), []DownloadItem{{ref, src}}, t.TempDir()); len(errs) != 0 {
+		t.Fatalf("DownloadMany() errors = %v", errs)
+	}
+
+	requests := h.find("bcr: request")
+	if len(requests) != 2 {
+		t.Fatalf("logged %d requests, want 2: %v", len(requests), requests)
+	}
+	for i, want := range []struct{ url, status string }{
+		{src.URLs[0], "404"},
+		{src.URLs[1], "200"},
+	} {
+		got := requests[i]
+		if got["url"] != want.url || got["status"] != want.status || got["module"] != "zlib" || got["version"] != "1.3.1" {
+			t.Errorf("request %d logged as %v, want url %s with status %s", i, got, want.url, want.status)
+		}
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d metric events, want 2: %+v", len(events), events)
+	}
+	if events[0].Path != src.URLs[0] || events[0].StatusCode != http.StatusNotFound || events[0].Err == nil {
+		t.Errorf("first event = %+v, want a failed request for %s", events[0], src.URLs[0])
+	}
+	if events[1].Path != src.URLs[1] || events[1].StatusCode != http.StatusOK || events[1].Err != nil {
+		t.Errorf("second event = %+v, want a successful request for %s", events[1], src.URLs[1])
+	}
+}
@@ -1,8 +1,11 @@
 package bcr
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
+	"time"
 )
 
 // ErrNotFound is returned when a module or version does not exist.
@@ -10,10 +13,26 @@ import (
 // [*NotFoundError] to get detailed information.
 var ErrNotFound = errors.New("bcr: not found")
 
+// ErrNoVersions is returned when a module exists but its metadata lists
+// no versions, as for a newly registered module. Errors matching
+// ErrNoVersions also match [ErrNotFound]; check for ErrNoVersions first
+// to tell the two cases apart.
+var ErrNoVersions = errors.New("bcr: module has no versions")
+
 // ErrListingNotSupported is returned when a registry does not support
 // listing modules (e.g., HTTP registry without index.json).
 var ErrListingNotSupported = errors.New("bcr: listing modules not supported")
 
+// ErrRateLimited is returned when the registry rejects a request with
+// 429 Too Many Requests. Use [errors.As] with [*RateLimitError] to get how
+// long the registry asked to wait.
+var ErrRateLimited = errors.New("bcr: rate limited")
+
+// ErrUnauthorized is returned when the registry rejects a request's
+// credentials, or requires credentials that were not given. Use
+// [errors.As] with [*AuthError] to get the URL and status code.
+var ErrUnauthorized = errors.New("bcr: unauthorized")
+
 // NotFoundError provides details about what was not found.
 type NotFoundError struct {
 	// Module is the module name that was queried.
@@ -46,6 +65,23 @@ func (e *NotFoundError) Unwrap() error {
 	return nil
 }
 
+// NoVersionsError indicates that a module exists but has no versions.
+type NoVersionsError struct {
+	// Module is the module name that was queried.
+	Module string
+}
+
+// Error implements the error interface.
+func (e *NoVersionsError) Error() string {
+	return fmt.Sprintf("bcr: module %q has no versions", e.Module)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrNoVersions] and [ErrNotFound].
+func (e *NoVersionsError) Is(target error) bool {
+	return target == ErrNoVersions || target == ErrNotFound
+}
+
 // RequestError indicates an error making an HTTP request.
 type RequestError struct {
 	// URL is the URL that was requested.
@@ -71,3 +107,339 @@ func (e *RequestError) Error() string {
 func (e *RequestError) Unwrap() error {
 	return e.Err
 }
+
+// RedirectError indicates that the registry answered with a redirect
+// that was not followed. See [WithNoRedirects].
+type RedirectError struct {
+	// URL is the URL that was requested.
+	URL string
+
+	// Location is the redirect target from the Location header, exactly
+	// as sent by the server. It may be relative to URL.
+	Location string
+
+	// StatusCode is the HTTP redirect status code (e.g., 301, 302).
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("bcr: request to %s redirected with status %d to %q", e.URL, e.StatusCode, e.Location)
+}
+
+// ErrYanked is returned by [Client.SourceStrict] when the requested
+// version has been yanked. Use [errors.As] with [*YankedError] to get the
+// reason.
+var ErrYanked = errors.New("bcr: version yanked")
+
+// YankedError indicates that a module version has been yanked.
+type YankedError struct {
+	// Module is the module name.
+	Module string
+
+	// Version is the yanked version.
+	Version string
+
+	// Reason is why the version was yanked, as given in the metadata.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *YankedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("bcr: module %q version %q is yanked: %s", e.Module, e.Version, e.Reason)
+	}
+	return fmt.Sprintf("bcr: module %q version %q is yanked", e.Module, e.Version)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrYanked].
+func (e *YankedError) Is(target error) bool {
+	return target == ErrYanked
+}
+
+// Unwrap returns nil (YankedError is a leaf error).
+func (e *YankedError) Unwrap() error {
+	return nil
+}
+
+// RateLimitError indicates that the registry answered 429 Too Many
+// Requests.
+type RateLimitError struct {
+	// URL is the URL that was requested.
+	URL string
+
+	// RetryAfter is how long the registry asked clients to wait before
+	// retrying, from the Retry-After header, or zero if it did not say.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("bcr: request to %s was rate limited; retry after %v", e.URL, e.RetryAfter)
+	}
+	return fmt.Sprintf("bcr: request to %s was rate limited", e.URL)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrRateLimited].
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Unwrap returns nil (RateLimitError is a leaf error).
+func (e *RateLimitError) Unwrap() error {
+	return nil
+}
+
+// AuthError indicates that the registry answered 401 Unauthorized or 403
+// Forbidden, so the request may succeed with other credentials. See
+// [WithBearerToken] and [WithBasicAuth].
+type AuthError struct {
+	// URL is the URL that was requested.
+	URL string
+
+	// StatusCode is the HTTP status code (401 or 403).
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("bcr: request to %s was not authorized (status %d)", e.URL, e.StatusCode)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrUnauthorized].
+func (e *AuthError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// Unwrap returns nil (AuthError is a leaf error).
+func (e *AuthError) Unwrap() error {
+	return nil
+}
+
+// IsTimeout reports whether err is the result of a request timing out,
+// either because its context deadline passed or because the HTTP client
+// or network reported a timeout.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Retryable reports whether a request that failed with err may succeed if
+// sent again. This is the policy [WithRetry] uses.
+//
+// Connection failures ([*RegistryUnavailableError]), network timeouts,
+// rate limiting ([*RateLimitError]) and 5xx responses are retryable.
+// Not-found, parse and integrity errors, other HTTP statuses, and errors
+// caused by the context being canceled or its deadline passing are not.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var (
+		unavailable *RegistryUnavailableError
+		rateErr     *RateLimitError
+		reqErr      *RequestError
+		netErr      net.Error
+	)
+	switch {
+	case errors.As(err, &unavailable), errors.As(err, &rateErr):
+		return true
+	case errors.As(err, &reqErr) && reqErr.StatusCode != 0:
+		return reqErr.StatusCode >= 500 && reqErr.StatusCode <= 599
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// IsCanceled reports whether err is the result of the request's context
+// being canceled. A deadline expiring is reported by [IsTimeout] instead.
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// ErrIntegrityMismatch is returned when downloaded content does not match
+// its expected integrity hash. Use [errors.As] with [*IntegrityError] to
+// get the expected and actual hashes.
+var ErrIntegrityMismatch = errors.New("bcr: integrity mismatch")
+
+// IntegrityError indicates that content did not match its expected
+// Subresource Integrity hash.
+type IntegrityError struct {
+	// Expected is the integrity string the content should have matched.
+	Expected string
+
+	// Actual is the integrity computed from the content, using the
+	// same algorithm as Expected.
+	Actual string
+}
+
+// Error implements the error interface.
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("bcr: integrity mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrIntegrityMismatch].
+func (e *IntegrityError) Is(target error) bool {
+	return target == ErrIntegrityMismatch
+}
+
+// ErrDownloadNotAllowed is returned when a download is refused by the
+// client's download policy, set with [WithAllowedSourceTypes] or
+// [WithAllowedDownloadHosts]. Use [errors.As] with
+// [*SourceTypeNotAllowedError] or [*HostNotAllowedError] for details.
+var ErrDownloadNotAllowed = errors.New("bcr: download not allowed")
+
+// SourceTypeNotAllowedError indicates that a module version was not
+// downloaded because [WithAllowedSourceTypes] excludes its source type.
+type SourceTypeNotAllowedError struct {
+	// Module is the module name.
+	Module string
+
+	// Version is the module version.
+	Version string
+
+	// Type is the rejected source type, as given by [Source.SourceType].
+	Type string
+}
+
+// Error implements the error interface.
+func (e *SourceTypeNotAllowedError) Error() string {
+	return fmt.Sprintf("bcr: %s source for %s@%s is not allowed", e.Type, e.Module, e.Version)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrDownloadNotAllowed].
+func (e *SourceTypeNotAllowedError) Is(target error) bool {
+	return target == ErrDownloadNotAllowed
+}
+
+// Unwrap returns nil (SourceTypeNotAllowedError is a leaf error).
+func (e *SourceTypeNotAllowedError) Unwrap() error {
+	return nil
+}
+
+// HostNotAllowedError indicates that a module version was not downloaded
+// because [WithAllowedDownloadHosts] excludes a host its source is
+// fetched from.
+type HostNotAllowedError struct {
+	// Module is the module name.
+	Module string
+
+	// Version is the module version.
+	Version string
+
+	// Host is the rejected host, or the whole location if it has no
+	// parseable host.
+	Host string
+}
+
+// Error implements the error interface.
+func (e *HostNotAllowedError) Error() string {
+	return fmt.Sprintf("bcr: source host %q for %s@%s is not allowed", e.Host, e.Module, e.Version)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrDownloadNotAllowed].
+func (e *HostNotAllowedError) Is(target error) bool {
+	return target == ErrDownloadNotAllowed
+}
+
+// Unwrap returns nil (HostNotAllowedError is a leaf error).
+func (e *HostNotAllowedError) Unwrap() error {
+	return nil
+}
+
+// ErrAttestationInvalid is returned when a version's attestation cannot
+// be verified. Use [errors.As] with [*AttestationError] for details.
+var ErrAttestationInvalid = errors.New("bcr: invalid attestation")
+
+// AttestationError indicates that the attestation of a module version is
+// missing, does not attest its source, or was rejected by a [Verifier].
+type AttestationError struct {
+	// Module is the module name.
+	Module string
+
+	// Version is the module version.
+	Version string
+
+	// Err is the reason the attestation was rejected, such as the error
+	// returned by the Verifier.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *AttestationError) Error() string {
+	return fmt.Sprintf("bcr: invalid attestation for %s@%s: %v", e.Module, e.Version, e.Err)
+}
+
+// Is reports whether this error matches the target.
+// Returns true for [ErrAttestationInvalid].
+func (e *AttestationError) Is(target error) bool {
+	return target == ErrAttestationInvalid
+}
+
+// Unwrap returns the underlying error.
+func (e *AttestationError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError indicates that a registry file could not be decoded or
+// contains invalid data.
+type ParseError struct {
+	// Module is the module whose file failed to parse.
+	Module string
+
+	// Version is the module version, or empty for module-level files
+	// such as metadata.json.
+	Version string
+
+	// File is the registry file name (e.g., "source.json").
+	File string
+
+	// Err is the underlying decoding or validation error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.Version != "" {
+		return fmt.Sprintf("bcr: failed to parse %s for %s@%s: %v", e.File, e.Module, e.Version, e.Err)
+	}
+	return fmt.Sprintf("bcr: failed to parse %s for %s: %v", e.File, e.Module, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// RegistryUnavailableError indicates that the registry could not be
+// reached at all, for example because of a DNS failure or a refused
+// connection. It is distinct from [RequestError], which reports requests
+// the server answered with an error status.
+type RegistryUnavailableError struct {
+	// URL is the URL that was requested.
+	URL string
+
+	// Err is the underlying network error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RegistryUnavailableError) Error() string {
+	return fmt.Sprintf("bcr: registry unavailable at %s: %v", e.URL, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RegistryUnavailableError) Unwrap() error {
+	return e.Err
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -97,7 +99,7 @@ func (r *FileRegistry) Metadata(ctx context.Context, module string) (*Metadata,
 
 	var meta Metadata
 	if err := json.Unmarshal(data, &meta); err != nil {
-		return nil, fmt.Errorf("bcr: failed to parse metadata for %s: %w", module, err)
+		return nil, &ParseError{Module: module, File: "metadata.json", Err: err}
 	}
 
 	return &meta, nil
@@ -120,7 +122,7 @@ func (r *FileRegistry) Source(ctx context.Context, module, version string) (*Sou
 
 	var src Source
 	if err := json.Unmarshal(data, &src); err != nil {
-		return nil, fmt.Errorf("bcr: failed to parse source for %s@%s: %w", module, version, err)
+		return nil, &ParseError{Module: module, Version: version, File: "source.json", Err: err}
 	}
 
 	return &src, nil
@@ -183,6 +185,94 @@ func (r *FileRegistry) ListModules(ctx context.Context) ([]string, error) {
 	return modules, nil
 }
 
+// FindModules returns module names that start with prefix, compared
+// case-insensitively, in directory order.
+//
+// Directory entries are read in batches and reading stops as soon as
+// limit matches are found; a limit of zero or less means no limit.
+func (r *FileRegistry) FindModules(ctx context.Context, prefix string, limit int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	modulesDir := filepath.Join(r.root, "modules")
+	dir, err := os.Open(modulesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrListingNotSupported
+		}
+		return nil, fmt.Errorf("bcr: failed to list modules: %w", err)
+	}
+	defer dir.Close()
+
+	var matches []string
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		entries, err := dir.ReadDir(256)
+		for _, entry := range entries {
+			if !entry.IsDir() || !hasPrefixFold(entry.Name(), prefix) {
+				continue
+			}
+			metaPath := filepath.Join(modulesDir, entry.Name(), "metadata.json")
+			if _, err := os.Stat(metaPath); err != nil {
+				continue
+			}
+			matches = append(matches, entry.Name())
+			if limit > 0 && len(matches) == limit {
+				return matches, nil
+			}
+		}
+		if err == io.EOF {
+			return matches, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bcr: failed to list modules: %w", err)
+		}
+	}
+}
+
+// SearchModules returns the module names containing query, compared
+// case-insensitively, in sorted order. See [Client.SearchModules].
+func (r *FileRegistry) SearchModules(ctx context.Context, query string) ([]string, error) {
+	modules, err := r.ListModules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchNames(modules, query), nil
+}
+
+// WriteIndex writes modules/index.json listing every module in the
+// registry, so that the directory can be served to [Client.ListModules].
+//
+// Modules are found with [FileRegistry.ListModules] and written in
+// sorted order, so repeated calls on an unchanged registry produce
+// identical files. The file is replaced atomically.
+func (r *FileRegistry) WriteIndex(ctx context.Context) error {
+	modules, err := r.ListModules(ctx)
+	if err != nil {
+		return err
+	}
+	if modules == nil {
+		modules = []string{}
+	}
+	slices.Sort(modules)
+
+	data, err := json.MarshalIndent(modules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("bcr: failed to encode module index: %w", err)
+	}
+	data = append(data, '\n')
+
+	indexPath := filepath.Join(r.root, "modules", "index.json")
+	if err := writeFileAtomic(indexPath, data, 0o644); err != nil {
+		return fmt.Errorf("bcr: failed to write module index: %w", err)
+	}
+	return nil
+}
+
 // Ensure FileRegistry implements Registry at compile time.
 var _ Registry = (*FileRegistry)(nil)
 
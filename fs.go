@@ -0,0 +1,176 @@
+package bcr
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+// FSRegistry is a Registry backed by an [fs.FS], such as a registry
+// snapshot embedded with go:embed.
+//
+// The filesystem must follow the same directory structure as
+// [FileRegistry], with modules/ at its root.
+type FSRegistry struct {
+	fsys fs.FS
+}
+
+// NewFSRegistry creates a registry that reads from fsys. Use [fs.Sub] if
+// the registry is in a subdirectory of fsys.
+func NewFSRegistry(fsys fs.FS) *FSRegistry {
+	return &FSRegistry{fsys: fsys}
+}
+
+// NewFSRegistryFromTar creates a registry from a tar archive of a registry
+// directory tree, such as a snapshot of the Bazel Central Registry, held
+// in memory. This lets tests and benchmarks load realistic data without
+// unpacking it to disk. Wrap r with [compress/gzip.NewReader] for a
+// .tar.gz file.
+//
+// The archive must have modules/ at its root; a leading "./" on entry
+// names is accepted. Only regular files and directories are loaded, and
+// an entry whose name is absolute or contains ".." is rejected.
+func NewFSRegistryFromTar(r io.Reader) (*FSRegistry, error) {
+	fsys := fstest.MapFS{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bcr: failed to read registry tar: %w", err)
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, "./"), "/")
+		if name == "" || name == "." {
+			continue
+		}
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("bcr: invalid path %q in registry tar", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			fsys[name] = &fstest.MapFile{Mode: fs.ModeDir | 0o755, ModTime: hdr.ModTime}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("bcr: failed to read %s from registry tar: %w", name, err)
+			}
+			fsys[name] = &fstest.MapFile{Data: data, Mode: 0o644, ModTime: hdr.ModTime}
+		}
+	}
+	return NewFSRegistry(fsys), nil
+}
+
+// Metadata fetches module metadata from the filesystem.
+func (r *FSRegistry) Metadata(ctx context.Context, module string) (*Metadata, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(r.fsys, path.Join("modules", module, "metadata.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &NotFoundError{Module: module}
+		}
+		return nil, fmt.Errorf("bcr: failed to read metadata for %s: %w", module, err)
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, &ParseError{Module: module, File: "metadata.json", Err: err}
+	}
+
+	return &meta, nil
+}
+
+// Source fetches source information from the filesystem.
+func (r *FSRegistry) Source(ctx context.Context, module, version string) (*Source, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(r.fsys, path.Join("modules", module, version, "source.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &NotFoundError{Module: module, Version: version}
+		}
+		return nil, fmt.Errorf("bcr: failed to read source for %s@%s: %w", module, version, err)
+	}
+
+	var src Source
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, &ParseError{Module: module, Version: version, File: "source.json", Err: err}
+	}
+
+	return &src, nil
+}
+
+// ModuleFile fetches the MODULE.bazel content from the filesystem.
+func (r *FSRegistry) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(r.fsys, path.Join("modules", module, version, "MODULE.bazel"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &NotFoundError{Module: module, Version: version}
+		}
+		return nil, fmt.Errorf("bcr: failed to read MODULE.bazel for %s@%s: %w", module, version, err)
+	}
+
+	return data, nil
+}
+
+// String returns a string representation of the registry.
+func (r *FSRegistry) String() string {
+	return "fs"
+}
+
+// Type returns the registry type ("fs").
+func (r *FSRegistry) Type() string {
+	return "fs"
+}
+
+// ListModules returns all module names in the registry.
+func (r *FSRegistry) ListModules(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(r.fsys, "modules")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrListingNotSupported
+		}
+		return nil, fmt.Errorf("bcr: failed to list modules: %w", err)
+	}
+
+	var modules []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// Verify it's a valid module (has metadata.json)
+			if _, err := fs.Stat(r.fsys, path.Join("modules", entry.Name(), "metadata.json")); err == nil {
+				modules = append(modules, entry.Name())
+			}
+		}
+	}
+
+	return modules, nil
+}
+
+// Ensure FSRegistry implements Registry at compile time.
+var _ Registry = (*FSRegistry)(nil)
+
+// Ensure FSRegistry implements ModuleLister at compile time.
+var _ ModuleLister = (*FSRegistry)(nil)
@@ -0,0 +1,172 @@
+package bcr
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSRegistry(t *testing.T) {
+	fsys := fstest.MapFS{
+		"modules/testmod/metadata.json":      {Data: []byte(`{"versions": ["1.0.0", "1.1.0"]}`)},
+		"modules/testmod/1.0.0/source.json":  {Data: []byte(`{"url": "https://example.com/testmod-1.0.0.tar.gz", "integrity": "sha256-abc"}`)},
+		"modules/testmod/1.0.0/MODULE.bazel": {Data: []byte(`module(name = "testmod", version = "1.0.0")`)},
+		"modules/other/metadata.json":        {Data: []byte(`{"versions": ["0.1.0"]}`)},
+		"modules/broken/metadata.json":       {Data: []byte(`{not json`)},
+		"modules/notamodule/README.md":       {Data: []byte("no metadata")},
+		"modules/index.json":                 {Data: []byte(`["testmod"]`)},
+	}
+	reg := NewFSRegistry(fsys)
+	ctx := context.Background()
+
+	t.Run("Metadata", func(t *testing.T) {
+		meta, err := reg.Metadata(ctx, "testmod")
+		if err != nil {
+			t.Fatalf("Metadata() error = %v", err)
+		}
+		if !slices.Equal(meta.Versions, []string{"1.0.0", "1.1.0"}) {
+			t.Errorf("Versions = %v", meta.Versions)
+		}
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		src, err := reg.Source(ctx, "testmod", "1.0.0")
+		if err != nil {
+			t.Fatalf("Source() error = %v", err)
+		}
+		if src.URL != "https://example.com/testmod-1.0.0.tar.gz" {
+			t.Errorf("URL = %q", src.URL)
+		}
+	})
+
+	t.Run("ModuleFile", func(t *testing.T) {
+		data, err := reg.ModuleFile(ctx, "testmod", "1.0.0")
+		if err != nil {
+			t.Fatalf("ModuleFile() error = %v", err)
+		}
+		if string(data) != `module(name = "testmod", version = "1.0.0")` {
+			t.Errorf("ModuleFile() = %q", data)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var notFound *NotFoundError
+		if _, err := reg.Metadata(ctx, "missing"); !errors.As(err, &notFound) || notFound.Module != "missing" {
+			t.Errorf("Metadata() error = %v, want *NotFoundError", err)
+		}
+		if _, err := reg.Source(ctx, "testmod", "9.9.9"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Source() error = %v, want ErrNotFound", err)
+		}
+		if _, err := reg.ModuleFile(ctx, "testmod", "9.9.9"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ModuleFile() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		var parseErr *ParseError
+		if _, err := reg.Metadata(ctx, "broken"); !errors.As(err, &parseErr) {
+			t.Errorf("Metadata() error = %v, want *ParseError", err)
+		}
+	})
+
+	t.Run("ListModules", func(t *testing.T) {
+		modules, err := reg.ListModules(ctx)
+		if err != nil {
+			t.Fatalf("ListModules() error = %v", err)
+		}
+		slices.Sort(modules)
+		if want := []string{"broken", "other", "testmod"}; !slices.Equal(modules, want) {
+			t.Errorf("ListModules() = %v, want %v", modules, want)
+		}
+
+		if _, err := NewFSRegistry(fstest.MapFS{}).ListModules(ctx); !errors.Is(err, ErrListingNotSupported) {
+			t.Errorf("ListModules() on empty fs error = %v, want ErrListingNotSupported", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := reg.Metadata(ctx, "testmod"); !errors.Is(err, context.Canceled) {
+			t.Errorf("Metadata() error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+// tarOf returns a tar archive holding the given directories and files.
+func tarOf(t *testing.T, dirs []string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, dir := range dirs {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir, Mode: 0o755}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(files[name]))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewFSRegistryFromTar(t *testing.T) {
+	ctx := context.Background()
+
+	archive := tarOf(t, []string{"./", "./modules/", "./modules/testmod/"}, map[string]string{
+		"./modules/testmod/metadata.json":      `{"versions": ["1.0.0", "1.1.0"]}`,
+		"./modules/testmod/1.0.0/MODULE.bazel": `module(name = "testmod", version = "1.0.0")`,
+		"modules/other/metadata.json":          `{"versions": ["0.1.0"]}`,
+	})
+	reg, err := NewFSRegistryFromTar(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("NewFSRegistryFromTar() error = %v", err)
+	}
+
+	meta, err := reg.Metadata(ctx, "testmod")
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if !slices.Equal(meta.Versions, []string{"1.0.0", "1.1.0"}) {
+		t.Errorf("Versions = %v", meta.Versions)
+	}
+	if data, err := reg.ModuleFile(ctx, "testmod", "1.0.0"); err != nil || string(data) != `module(name = "testmod", version = "1.0.0")` {
+		t.Errorf("ModuleFile() = %q, %v", data, err)
+	}
+	modules, err := reg.ListModules(ctx)
+	if err != nil {
+		t.Fatalf("ListModules() error = %v", err)
+	}
+	slices.Sort(modules)
+	if want := []string{"other", "testmod"}; !slices.Equal(modules, want) {
+		t.Errorf("ListModules() = %v, want %v", modules, want)
+	}
+
+	t.Run("path traversal", func(t *testing.T) {
+		for _, name := range []string{"../etc/passwd", "/modules/x/metadata.json", "modules/../../x"} {
+			archive := tarOf(t, nil, map[string]string{name: "{}"})
+			if _, err := NewFSRegistryFromTar(bytes.NewReader(archive)); err == nil {
+				t.Errorf("NewFSRegistryFromTar() with entry %q should fail", name)
+			}
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := NewFSRegistryFromTar(bytes.NewReader(archive[:700])); err == nil {
+			t.Error("NewFSRegistryFromTar() with a truncated archive should fail")
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package bcr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitRegistry is a [FileRegistry] reading a git checkout of the
+// bazel-central-registry repository, such as one cloned for use in an
+// air-gapped environment. In addition to the FileRegistry methods, it
+// reports the commit that is checked out, so tools can record which
+// registry revision they used.
+type GitRegistry struct {
+	*FileRegistry
+}
+
+// NewGitRegistry creates a registry reading the git checkout at
+// repoPath. An error is returned if repoPath does not look like a
+// registry checkout: it must contain a modules directory and a .git
+// directory, or a .git file as created for worktrees and submodules.
+func NewGitRegistry(repoPath string) (*GitRegistry, error) {
+	if info, err := os.Stat(filepath.Join(repoPath, "modules")); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("bcr: %s is not a registry: no modules directory", repoPath)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".git")); err != nil {
+		return nil, fmt.Errorf("bcr: %s is not a git checkout: no .git", repoPath)
+	}
+	return &GitRegistry{FileRegistry: NewFileRegistry(repoPath)}, nil
+}
+
+// String returns a string representation of the registry.
+func (r *GitRegistry) String() string {
+	return "git+file://" + r.root
+}
+
+// Type returns the registry type ("git").
+func (r *GitRegistry) Type() string {
+	return "git"
+}
+
+// Commit returns the full hash of the commit checked out, read from the
+// repository's HEAD without running git. Loose and packed refs are
+// supported.
+func (r *GitRegistry) Commit() (string, error) {
+	gitDir, err := r.gitDir()
+	if err != nil {
+		return "", err
+	}
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("bcr: failed to read HEAD: %w", err)
+	}
+
+	ref, symbolic := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !symbolic {
+		return checkCommitHash(ref)
+	}
+
+	// Branch refs live in the common directory of a worktree.
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = resolveGitPath(gitDir, strings.TrimSpace(string(data)))
+	}
+	for _, dir := range []string{gitDir, commonDir} {
+		if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			return checkCommitHash(strings.TrimSpace(string(data)))
+		}
+	}
+	hash, err := packedRef(filepath.Join(commonDir, "packed-refs"), ref)
+	if err != nil {
+		return "", err
+	}
+	return checkCommitHash(hash)
+}
+
+// gitDir returns the repository's git directory, following a .git file's
+// "gitdir:" pointer.
+func (r *GitRegistry) gitDir() (string, error) {
+	dotGit := filepath.Join(r.root, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("bcr: %s is not a git checkout: %w", r.root, err)
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("bcr: failed to read %s: %w", dotGit, err)
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("bcr: %s does not point to a git directory", dotGit)
+	}
+	return resolveGitPath(r.root, dir), nil
+}
+
+// resolveGitPath resolves p, as written in a git file, relative to base.
+func resolveGitPath(base, p string) string {
+	p = filepath.FromSlash(p)
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+// packedRef looks up ref in a packed-refs file.
+func packedRef(name, ref string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("bcr: ref %s not found", ref)
+		}
+		return "", fmt.Errorf("bcr: failed to read packed refs: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines are "<hash> <ref>"; comments start with '#' and peeled
+		// tag lines with '^'.
+		hash, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref {
+			return hash, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("bcr: failed to read packed refs: %w", err)
+	}
+	return "", fmt.Errorf("bcr: ref %s not found", ref)
+}
+
+// checkCommitHash returns hash if it is a SHA-1 or SHA-256 object name.
+func checkCommitHash(hash string) (string, error) {
+	if len(hash) != 40 && len(hash) != 64 {
+		return "", fmt.Errorf("bcr: invalid commit hash %q", hash)
+	}
+	for i := 0; i < len(hash); i++ {
+		if c := hash[i]; !isDigit(c) && (c < 'a' || c > 'f') {
+			return "", fmt.Errorf("bcr: invalid commit hash %q", hash)
+		}
+	}
+	return hash, nil
+}
+
+// Ensure GitRegistry implements Registry at compile time.
+var _ Registry = (*GitRegistry)(nil)
+
+// Ensure GitRegistry implements ModuleLister at compile time.
+var _ ModuleLister = (*GitRegistry)(nil)
@@ -0,0 +1,156 @@
+package bcr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testCommit      = "0123456789abcdef0123456789abcdef01234567"
+	testOtherCommit = "89abcdef0123456789abcdef0123456789abcdef"
+)
+
+// newGitCheckout creates a fake registry checkout with one module and
+// the given files under .git.
+func newGitCheckout(t *testing.T, gitFiles map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"modules/zlib/metadata.json":      `{"versions":["1.3.1"]}`,
+		"modules/zlib/1.3.1/source.json":  `{"url":"https://example.com/zlib.tar.gz"}`,
+		"modules/zlib/1.3.1/MODULE.bazel": `module(name = "zlib", version = "1.3.1")`,
+		".git/config":                     "[core]\n",
+	}
+	for name, content := range gitFiles {
+		files[".git/"+name] = content
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGitRegistry(t *testing.T) {
+	dir := newGitCheckout(t, map[string]string{
+		"HEAD":            "ref: refs/heads/main\n",
+		"refs/heads/main": testCommit + "\n",
+	})
+	reg, err := NewGitRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewGitRegistry() error = %v", err)
+	}
+
+	src, err := reg.Source(context.Background(), "zlib", "1.3.1")
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if src.URL != "https://example.com/zlib.tar.gz" {
+		t.Errorf("URL = %q", src.URL)
+	}
+	if got := reg.Type(); got != "git" {
+		t.Errorf("Type() = %q, want git", got)
+	}
+	if got := reg.String(); got != "git+file://"+dir {
+		t.Errorf("String() = %q", got)
+	}
+
+	commit, err := reg.Commit()
+	if err != nil || commit != testCommit {
+		t.Errorf("Commit() = %q, %v; want %q", commit, err, testCommit)
+	}
+}
+
+func TestGitRegistryCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		gitFiles map[string]string
+		want     string
+		wantErr  string
+	}{
+		{"detached", map[string]string{"HEAD": testCommit + "\n"}, testCommit, ""},
+		{"packed ref", map[string]string{
+			"HEAD":        "ref: refs/heads/main\n",
+			"packed-refs": "# pack-refs with: peeled fully-peeled sorted\n" + testOtherCommit + " refs/heads/dev\n" + testCommit + " refs/heads/main\n",
+		}, testCommit, ""},
+		{"loose ref wins over packed", map[string]string{
+			"HEAD":            "ref: refs/heads/main\n",
+			"refs/heads/main": testOtherCommit + "\n",
+			"packed-refs":     testCommit + " refs/heads/main\n",
+		}, testOtherCommit, ""},
+		{"unborn branch", map[string]string{"HEAD": "ref: refs/heads/main\n"}, "", "not found"},
+		{"corrupt HEAD", map[string]string{"HEAD": "garbage\n"}, "", "invalid commit hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := NewGitRegistry(newGitCheckout(t, tt.gitFiles))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := reg.Commit()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Commit() = %q, %v; want error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Commit() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("worktree", func(t *testing.T) {
+		main := newGitCheckout(t, map[string]string{
+			"HEAD":            "ref: refs/heads/main\n",
+			"refs/heads/main": testCommit + "\n",
+			"refs/heads/dev":  testOtherCommit + "\n",
+			// A worktree's own directory has its HEAD and a pointer
+			// to the shared refs.
+			"worktrees/wt/HEAD":      "ref: refs/heads/dev\n",
+			"worktrees/wt/commondir": "../..\n",
+		})
+		wt := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(wt, "modules"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		gitdir := filepath.Join(main, ".git", "worktrees", "wt")
+		if err := os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: "+gitdir+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		reg, err := NewGitRegistry(wt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := reg.Commit(); err != nil || got != testOtherCommit {
+			t.Errorf("Commit() = %q, %v; want %q", got, err, testOtherCommit)
+		}
+	})
+}
+
+func TestNewGitRegistryValidation(t *testing.T) {
+	t.Run("no modules", func(t *testing.T) {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, ".git"), 0o755)
+		if _, err := NewGitRegistry(dir); err == nil || !strings.Contains(err.Error(), "modules") {
+			t.Errorf("error = %v, want missing modules error", err)
+		}
+	})
+
+	t.Run("no .git", func(t *testing.T) {
+		dir := t.TempDir()
+		os.Mkdir(filepath.Join(dir, "modules"), 0o755)
+		if _, err := NewGitRegistry(dir); err == nil || !strings.Contains(err.Error(), ".git") {
+			t.Errorf("error = %v, want missing .git error", err)
+		}
+	})
+}
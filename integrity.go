@@ -0,0 +1,207 @@
+package bcr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// integrityHashes maps SRI algorithm names to hash constructors.
+var integrityHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// ParseIntegrity splits a Subresource Integrity string (e.g.,
+// "sha256-BASE64") into its algorithm and decoded digest.
+//
+// Any algorithm name is accepted, so that integrities using algorithms
+// this package does not know can still be inspected. An error is returned
+// if s is not of the form <algo>-<base64> or the digest is not valid
+// base64.
+func ParseIntegrity(s string) (algo string, digest []byte, err error) {
+	algo, encoded, ok := strings.Cut(s, "-")
+	if !ok || algo == "" || encoded == "" {
+		return "", nil, fmt.Errorf("bcr: malformed integrity %q: want <algo>-<base64>", s)
+	}
+	digest, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("bcr: malformed integrity %q: %w", s, err)
+	}
+	return algo, digest, nil
+}
+
+// formatIntegrity encodes a digest as an SRI string.
+func formatIntegrity(algo string, digest []byte) string {
+	return algo + "-" + base64.StdEncoding.EncodeToString(digest)
+}
+
+// ComputeIntegrity returns the sha256 Subresource Integrity string
+// (e.g., "sha256-...") of data, in the form used by source.json.
+func ComputeIntegrity(data []byte) string {
+	sum := sha256.Sum256(data)
+	return formatIntegrity("sha256", sum[:])
+}
+
+// NewVerifyingReader returns a reader that passes through the content of r
+// while hashing it, and checks the digest against integrity at EOF.
+//
+// If the digest does not match, the final Read returns an [*IntegrityError]
+// instead of [io.EOF]. Consumers must therefore read to EOF, and must not
+// trust content until they have done so without error.
+//
+// integrity is a Subresource Integrity string using sha256, sha384 or
+// sha512 (e.g., "sha256-..."). An error is returned if it is malformed or
+// uses another algorithm.
+func NewVerifyingReader(r io.Reader, integrity string) (io.Reader, error) {
+	return newVerifyingReader(r, []string{integrity})
+}
+
+// newVerifyingReader is like [NewVerifyingReader] but accepts content
+// matching any of integrities.
+func newVerifyingReader(r io.Reader, integrities []string) (*verifyingReader, error) {
+	if len(integrities) == 0 {
+		return nil, errors.New("bcr: no integrity to verify against")
+	}
+	v := &verifyingReader{r: r}
+	for _, integrity := range integrities {
+		algo, digest, err := ParseIntegrity(integrity)
+		if err != nil {
+			return nil, err
+		}
+		newHash, ok := integrityHashes[algo]
+		if !ok {
+			return nil, fmt.Errorf("bcr: unsupported integrity algorithm %q", algo)
+		}
+		v.checks = append(v.checks, integrityCheck{
+			h:        newHash(),
+			algo:     algo,
+			expected: integrity,
+			digest:   digest,
+		})
+	}
+	return v, nil
+}
+
+// verifyingReader hashes content as it is read. See [NewVerifyingReader].
+type verifyingReader struct {
+	r      io.Reader
+	checks []integrityCheck
+	err    error // sticky result once EOF is reached
+}
+
+// integrityCheck is one expected digest of a [verifyingReader].
+type integrityCheck struct {
+	h        hash.Hash
+	algo     string
+	expected string
+	digest   []byte
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.r.Read(p)
+	for _, c := range v.checks {
+		c.h.Write(p[:n])
+	}
+	if err == io.EOF {
+		v.err = v.verify()
+		return n, v.err
+	}
+	return n, err
+}
+
+// verify returns io.EOF if any check matches, or an *IntegrityError for
+// the first expected integrity otherwise.
+func (v *verifyingReader) verify() error {
+	for _, c := range v.checks {
+		if bytes.Equal(c.h.Sum(nil), c.digest) {
+			return io.EOF
+		}
+	}
+	first := v.checks[0]
+	return &IntegrityError{
+		Expected: first.expected,
+		Actual:   formatIntegrity(first.algo, first.h.Sum(nil)),
+	}
+}
+
+// validateIntegrity checks that s is a well-formed SRI string using a
+// supported algorithm with a digest of the right length.
+func validateIntegrity(s string) error {
+	algo, digest, err := ParseIntegrity(s)
+	if err != nil {
+		return err
+	}
+	newHash, ok := integrityHashes[algo]
+	if !ok {
+		return fmt.Errorf("bcr: unsupported integrity algorithm %q", algo)
+	}
+	if size := newHash().Size(); len(digest) != size {
+		return fmt.Errorf("bcr: malformed integrity %q: %s digest must be %d bytes, got %d", s, algo, size, len(digest))
+	}
+	return nil
+}
+
+// ExpectedSHA256 returns the sha256 digest the source archive must have,
+// for comparison with [crypto/sha256.Sum256]. An error is returned if the
+// source has no sha256 integrity or it is malformed.
+func (s *Source) ExpectedSHA256() ([]byte, error) {
+	if s == nil {
+		return nil, errors.New("bcr: no source")
+	}
+	integrities := sourceIntegrities(s)
+	for _, integrity := range integrities {
+		if !strings.HasPrefix(integrity, "sha256-") {
+			continue
+		}
+		_, digest, err := ParseIntegrity(integrity)
+		if err != nil {
+			return nil, err
+		}
+		if len(digest) != sha256.Size {
+			return nil, fmt.Errorf("bcr: malformed integrity %q: sha256 digest must be %d bytes, got %d", integrity, sha256.Size, len(digest))
+		}
+		return digest, nil
+	}
+	if len(integrities) == 0 {
+		return nil, errors.New("bcr: source has no integrity")
+	}
+	return nil, fmt.Errorf("bcr: source has no sha256 integrity, got %q", integrities[0])
+}
+
+// sourceIntegrities returns every acceptable archive integrity of src.
+func sourceIntegrities(src *Source) []string {
+	if len(src.Integrities) == 0 && src.Integrity != "" {
+		return []string{src.Integrity}
+	}
+	return src.Integrities
+}
+
+// validateSourceIntegrity checks the archive and patch integrities of src.
+func validateSourceIntegrity(src *Source) error {
+	var errs []error
+	for _, integrity := range sourceIntegrities(src) {
+		if err := validateIntegrity(integrity); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(src.Patches)) {
+		if err := validateIntegrity(src.Patches[name]); err != nil {
+			errs = append(errs, fmt.Errorf("patch %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
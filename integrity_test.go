@@ -0,0 +1,266 @@
+package bcr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sha256Integrity(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestNewVerifyingReader(t *testing.T) {
+	content := []byte("archive contents")
+	integrity := sha256Integrity(content)
+
+	t.Run("match", func(t *testing.T) {
+		r, err := NewVerifyingReader(bytes.NewReader(content), integrity)
+		if err != nil {
+			t.Fatalf("NewVerifyingReader() error = %v", err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("content = %q, want %q", got, content)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		r, err := NewVerifyingReader(strings.NewReader("tampered"), integrity)
+		if err != nil {
+			t.Fatalf("NewVerifyingReader() error = %v", err)
+		}
+		_, err = io.ReadAll(r)
+		if !errors.Is(err, ErrIntegrityMismatch) {
+			t.Fatalf("error = %v, want ErrIntegrityMismatch", err)
+		}
+		var ie *IntegrityError
+		if !errors.As(err, &ie) {
+			t.Fatal("error should be *IntegrityError")
+		}
+		if ie.Expected != integrity {
+			t.Errorf("Expected = %q, want %q", ie.Expected, integrity)
+		}
+		if want := sha256Integrity([]byte("tampered")); ie.Actual != want {
+			t.Errorf("Actual = %q, want %q", ie.Actual, want)
+		}
+	})
+
+	t.Run("invalid integrity", func(t *testing.T) {
+		for _, s := range []string{"", "sha256", "sha256-!!!", "md5-AAAA"} {
+			if _, err := NewVerifyingReader(bytes.NewReader(content), s); err == nil {
+				t.Errorf("NewVerifyingReader(%q) should fail", s)
+			}
+		}
+	})
+}
+
+func TestValidateSourceIntegrity(t *testing.T) {
+	valid := sha256Integrity([]byte("archive"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/testmod/1.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{
+				URL:       "https://example.com/archive.zip",
+				Integrity: valid,
+				Patches:   map[string]string{"fix.patch": valid},
+			})
+		case "/modules/testmod/2.0.0/source.json":
+			json.NewEncoder(w).Encode(&Source{
+				URL:       "https://example.com/archive.zip",
+				Integrity: "sha256-abc123",
+				Patches:   map[string]string{"fix.patch": "not-sri"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	t.Run("lenient by default", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL))
+		if _, err := c.Source(ctx, "testmod", "2.0.0"); err != nil {
+			t.Errorf("Source() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithValidateSourceIntegrity())
+		if _, err := c.Source(ctx, "testmod", "1.0.0"); err != nil {
+			t.Errorf("Source() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		c := New(WithBaseURL(srv.URL), WithValidateSourceIntegrity())
+		_, err := c.Source(ctx, "testmod", "2.0.0")
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("error = %v, want *ParseError", err)
+		}
+		if pe.Module != "testmod" || pe.Version != "2.0.0" || pe.File != "source.json" {
+			t.Errorf("ParseError = %+v", pe)
+		}
+		if !strings.Contains(err.Error(), "fix.patch") {
+			t.Errorf("error should mention the bad patch: %v", err)
+		}
+	})
+}
+
+func TestValidateIntegrity(t *testing.T) {
+	tests := []struct {
+		integrity string
+		valid     bool
+	}{
+		{sha256Integrity([]byte("x")), true},
+		{"sha512-" + base64.StdEncoding.EncodeToString(make([]byte, 64)), true},
+		{"sha256-" + base64.StdEncoding.EncodeToString(make([]byte, 16)), false}, // wrong length
+		{"sha256-abc123", false},
+		{"md5-" + base64.StdEncoding.EncodeToString(make([]byte, 16)), false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.integrity, func(t *testing.T) {
+			err := validateIntegrity(tt.integrity)
+			if (err == nil) != tt.valid {
+				t.Errorf("validateIntegrity(%q) error = %v, want valid = %v", tt.integrity, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestComputeIntegrity(t *testing.T) {
+	data := []byte(`module(name = "testmod", version = "1.0.0")`)
+	got := ComputeIntegrity(data)
+	if want := sha256Integrity(data); got != want {
+		t.Errorf("ComputeIntegrity() = %q, want %q", got, want)
+	}
+	if err := validateIntegrity(got); err != nil {
+		t.Errorf("validateIntegrity(%q) error = %v", got, err)
+	}
+
+	// The empty input has a well-known digest.
+	if got := ComputeIntegrity(nil); got != "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" {
+		t.Errorf("ComputeIntegrity(nil) = %q", got)
+	}
+}
+
+func TestVerifyingReaderAny(t *testing.T) {
+	content := []byte("archive contents")
+	other := sha256Integrity([]byte("other contents"))
+
+	r, err := newVerifyingReader(bytes.NewReader(content), []string{other, sha256Integrity(content)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); err != nil {
+		t.Errorf("ReadAll() error = %v, want match on second integrity", err)
+	}
+
+	r, err = newVerifyingReader(bytes.NewReader(content), []string{other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(r)
+	var intErr *IntegrityError
+	if !errors.As(err, &intErr) || intErr.Expected != other {
+		t.Errorf("error = %v, want *IntegrityError expecting %s", err, other)
+	}
+
+	if _, err := newVerifyingReader(bytes.NewReader(content), nil); err == nil {
+		t.Error("expected error for no integrities")
+	}
+}
+
+func TestParseIntegrity(t *testing.T) {
+	data := []byte("archive")
+	sum := sha256.Sum256(data)
+
+	tests := []struct {
+		name     string
+		input    string
+		wantAlgo string
+		want     []byte
+		wantErr  bool
+	}{
+		{"sha256", sha256Integrity(data), "sha256", sum[:], false},
+		{"unsupported algorithm", "sha3-" + base64.StdEncoding.EncodeToString([]byte("digest")), "sha3", []byte("digest"), false},
+		{"empty", "", "", nil, true},
+		{"no digest", "sha256-", "", nil, true},
+		{"no algorithm", "-" + base64.StdEncoding.EncodeToString(sum[:]), "", nil, true},
+		{"no separator", "sha256", "", nil, true},
+		{"malformed base64", "sha256-not*base64", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			algo, digest, err := ParseIntegrity(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseIntegrity(%q) = %q, %x, want error", tt.input, algo, digest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseIntegrity(%q) error = %v", tt.input, err)
+			}
+			if algo != tt.wantAlgo || !bytes.Equal(digest, tt.want) {
+				t.Errorf("ParseIntegrity(%q) = %q, %x, want %q, %x", tt.input, algo, digest, tt.wantAlgo, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectedSHA256(t *testing.T) {
+	data := []byte("archive")
+	sum := sha256.Sum256(data)
+	sha512Integrity := "sha512-" + base64.StdEncoding.EncodeToString(make([]byte, 64))
+
+	t.Run("integrity", func(t *testing.T) {
+		got, err := (&Source{Integrity: sha256Integrity(data)}).ExpectedSHA256()
+		if err != nil {
+			t.Fatalf("ExpectedSHA256() error = %v", err)
+		}
+		if !bytes.Equal(got, sum[:]) {
+			t.Errorf("ExpectedSHA256() = %x, want %x", got, sum)
+		}
+	})
+
+	t.Run("integrities", func(t *testing.T) {
+		src := &Source{Integrities: []string{sha512Integrity, sha256Integrity(data)}}
+		got, err := src.ExpectedSHA256()
+		if err != nil || !bytes.Equal(got, sum[:]) {
+			t.Errorf("ExpectedSHA256() = %x, %v, want %x", got, err, sum)
+		}
+	})
+
+	for name, src := range map[string]*Source{
+		"nil":       nil,
+		"empty":     {},
+		"sha512":    {Integrity: sha512Integrity},
+		"malformed": {Integrity: "sha256-not*base64"},
+		"short":     {Integrity: "sha256-" + base64.StdEncoding.EncodeToString([]byte("short"))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got, err := src.ExpectedSHA256(); err == nil {
+				t.Errorf("ExpectedSHA256() = %x, want error", got)
+			}
+		})
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // TestModuleListerInterface verifies implementations.
@@ -119,3 +124,604 @@ func TestClientListModules(t *testing.T) {
 		}
 	})
 }
+
+func TestFindModules(t *testing.T) {
+	modules := []string{"rules_go", "rules_python", "Rules_Rust", "protobuf"}
+
+	dir := t.TempDir()
+	for _, mod := range modules {
+		modDir := filepath.Join(dir, "modules", mod)
+		if err := os.MkdirAll(modDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modDir, "metadata.json"), []byte(`{"versions":[]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/index.json" {
+			json.NewEncoder(w).Encode(modules)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	finders := map[string]interface {
+		FindModules(ctx context.Context, prefix string, limit int) ([]string, error)
+	}{
+		"Client":       New(WithBaseURL(srv.URL)),
+		"FileRegistry": NewFileRegistry(dir),
+	}
+	ctx := context.Background()
+
+	for name, f := range finders {
+		t.Run(name, func(t *testing.T) {
+			got, err := f.FindModules(ctx, "RULES_", 0)
+			if err != nil {
+				t.Fatalf("FindModules() error = %v", err)
+			}
+			slices.Sort(got)
+			want := []string{"Rules_Rust", "rules_go", "rules_python"}
+			if !slices.Equal(got, want) {
+				t.Errorf("FindModules() = %v, want %v", got, want)
+			}
+
+			got, err = f.FindModules(ctx, "rules_", 2)
+			if err != nil {
+				t.Fatalf("FindModules() error = %v", err)
+			}
+			if len(got) != 2 {
+				t.Errorf("FindModules() with limit 2 = %v", got)
+			}
+
+			got, err = f.FindModules(ctx, "nomatch", 0)
+			if err != nil {
+				t.Fatalf("FindModules() error = %v", err)
+			}
+			if len(got) != 0 {
+				t.Errorf("FindModules() = %v, want empty", got)
+			}
+		})
+	}
+
+	t.Run("listing not supported", func(t *testing.T) {
+		_, err := NewFileRegistry(t.TempDir()).FindModules(ctx, "rules_", 0)
+		if !errors.Is(err, ErrListingNotSupported) {
+			t.Errorf("error = %v, want ErrListingNotSupported", err)
+		}
+	})
+}
+
+func TestSearchModules(t *testing.T) {
+	modules := []string{"rules_python", "rules_go", "Rules_Rust", "protobuf", "grpc"}
+
+	dir := t.TempDir()
+	for _, mod := range modules {
+		modDir := filepath.Join(dir, "modules", mod)
+		if err := os.MkdirAll(modDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modDir, "metadata.json"), []byte(`{"versions":[]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/index.json" {
+			json.NewEncoder(w).Encode(modules)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	searchers := map[string]interface {
+		SearchModules(ctx context.Context, query string) ([]string, error)
+	}{
+		"Client":       New(WithBaseURL(srv.URL)),
+		"FileRegistry": NewFileRegistry(dir),
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"RULES_", []string{"Rules_Rust", "rules_go", "rules_python"}},
+		{"ru", []string{"Rules_Rust", "rules_go", "rules_python"}},
+		{"pc", []string{"grpc"}},
+		{"o", []string{"protobuf", "rules_go", "rules_python"}},
+		{"nomatch", nil},
+	}
+	for name, s := range searchers {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				got, err := s.SearchModules(ctx, tt.query)
+				if err != nil {
+					t.Fatalf("SearchModules(%q) error = %v", tt.query, err)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("SearchModules(%q) = %v, want %v", tt.query, got, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("listing not supported", func(t *testing.T) {
+		_, err := NewFileRegistry(t.TempDir()).SearchModules(ctx, "rules")
+		if !errors.Is(err, ErrListingNotSupported) {
+			t.Errorf("error = %v, want ErrListingNotSupported", err)
+		}
+	})
+}
+
+func TestFileRegistryWriteIndex(t *testing.T) {
+	dir := t.TempDir()
+	for _, mod := range []string{"rules_python", "protobuf", "rules_go"} {
+		modDir := filepath.Join(dir, "modules", mod)
+		if err := os.MkdirAll(modDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modDir, "metadata.json"), []byte(`{"versions":[]}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg := NewFileRegistry(dir)
+	ctx := context.Background()
+	indexPath := filepath.Join(dir, "modules", "index.json")
+
+	if err := reg.WriteIndex(ctx); err != nil {
+		t.Fatalf("WriteIndex() error = %v", err)
+	}
+	first, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("served to client", func(t *testing.T) {
+		srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+		defer srv.Close()
+
+		got, err := New(WithBaseURL(srv.URL)).ListModules(ctx)
+		if err != nil {
+			t.Fatalf("ListModules() error = %v", err)
+		}
+		want := []string{"protobuf", "rules_go", "rules_python"}
+		if !slices.Equal(got, want) {
+			t.Errorf("ListModules() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		if err := reg.WriteIndex(ctx); err != nil {
+			t.Fatalf("WriteIndex() error = %v", err)
+		}
+		second, err := os.ReadFile(indexPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(first) != string(second) {
+			t.Errorf("index changed between calls:\n%s\n%s", first, second)
+		}
+	})
+
+	t.Run("empty registry", func(t *testing.T) {
+		emptyDir := t.TempDir()
+		os.MkdirAll(filepath.Join(emptyDir, "modules"), 0o755)
+		if err := NewFileRegistry(emptyDir).WriteIndex(ctx); err != nil {
+			t.Fatalf("WriteIndex() error = %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(emptyDir, "modules", "index.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "[]\n" {
+			t.Errorf("index = %q, want %q", data, "[]\n")
+		}
+	})
+
+	t.Run("no modules directory", func(t *testing.T) {
+		err := NewFileRegistry(t.TempDir()).WriteIndex(ctx)
+		if !errors.Is(err, ErrListingNotSupported) {
+			t.Errorf("error = %v, want ErrListingNotSupported", err)
+		}
+	})
+}
+
+func TestSnapshotMetadata(t *testing.T) {
+	modules := []string{"rules_go", "rules_python", "protobuf", "broken"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modules/index.json":
+			json.NewEncoder(w).Encode(modules)
+		case "/modules/broken/metadata.json":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/modules/rules_go/metadata.json",
+			"/modules/rules_python/metadata.json",
+			"/modules/protobuf/metadata.json":
+			json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	t.Run("partial failure", func(t *testing.T) {
+		got, err := New(WithBaseURL(srv.URL)).SnapshotMetadata(ctx)
+		if err == nil {
+			t.Fatal("expected error for broken module")
+		}
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("error = %v, want *RequestError with status 500", err)
+		}
+		if len(got) != 3 {
+			t.Errorf("got %d modules, want 3", len(got))
+		}
+		for _, mod := range []string{"rules_go", "rules_python", "protobuf"} {
+			if got[mod] == nil || got[mod].Latest() != "1.0.0" {
+				t.Errorf("snapshot[%q] = %+v", mod, got[mod])
+			}
+		}
+	})
+
+	t.Run("listing not supported", func(t *testing.T) {
+		noIndex := httptest.NewServer(http.NotFoundHandler())
+		defer noIndex.Close()
+
+		_, err := New(WithBaseURL(noIndex.URL)).SnapshotMetadata(ctx)
+		if !errors.Is(err, ErrListingNotSupported) {
+			t.Errorf("error = %v, want ErrListingNotSupported", err)
+		}
+	})
+}
+
+func TestMetadataBatch(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		module := strings.Split(r.URL.Path, "/")[2]
+		if strings.HasPrefix(module, "missing") {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&Metadata{Versions: []string{"1.0.0"}, Homepage: module})
+	}))
+	defer srv.Close()
+
+	var modules []string
+	for i := range 12 {
+		modules = append(modules, fmt.Sprintf("mod%d", i))
+	}
+	modules = append(modules, "missing1", "mod0")
+
+	const limit = 3
+	c := New(WithBaseURL(srv.URL), WithMaxConcurrency(limit))
+	metas, errs := c.MetadataBatch(context.Background(), modules)
+
+	if len(metas) != 12 {
+		t.Errorf("got %d results, want 12", len(metas))
+	}
+	for module, meta := range metas {
+		if meta.Homepage != module {
+			t.Errorf("metas[%q] holds metadata for %q", module, meta.Homepage)
+		}
+	}
+	if len(errs) != 1 {
+		t.Errorf("got %d errors, want 1: %v", len(errs), errs)
+	}
+	var notFound *NotFoundError
+	if !errors.As(errs["missing1"], &notFound) || notFound.Module != "missing1" {
+		t.Errorf("errs[missing1] = %v, want *NotFoundError", errs["missing1"])
+	}
+	if p := peak.Load(); p > limit {
+		t.Errorf("peak concurrency = %d, want at most %d", p, limit)
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		metas, errs := c.MetadataBatch(ctx, []string{"mod1", "mod2"})
+		if len(metas) != 0 {
+			t.Errorf("got %d results, want none", len(metas))
+		}
+		for _, module := range []string{"mod1", "mod2"} {
+			if !errors.Is(errs[module], context.Canceled) {
+				t.Errorf("errs[%q] = %v, want context.Canceled", module, errs[module])
+			}
+		}
+	})
+}
+
+func TestModulesByMaintainer(t *testing.T) {
+	dir := t.TempDir()
+	metadata := map[string]string{
+		"rules_go":     `{"versions":["1.0.0"],"maintainers":[{"name":"A","github":"alice"},{"github":"bob"}]}`,
+		"rules_python": `{"versions":["1.0.0"],"maintainers":[{"github":"Alice"}]}`,
+		"protobuf":     `{"versions":["1.0.0"],"maintainers":[{"github":"bob"}]}`,
+		"zlib":         `{"versions":["1.0.0"],"maintainers":[{"email":"carol@example.com"}]}`,
+	}
+	for mod, data := range metadata {
+		modDir := filepath.Join(dir, "modules", mod)
+		if err := os.MkdirAll(modDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modDir, "metadata.json"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	if err := NewFileRegistry(dir).WriteIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	client := New(WithBaseURL(srv.URL), WithMaxConcurrency(2))
+
+	tests := []struct {
+		user string
+		want []string
+	}{
+		{"alice", []string{"rules_go", "rules_python"}},
+		{"BOB", []string{"protobuf", "rules_go"}},
+		{"carol", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := client.ModulesByMaintainer(ctx, tt.user)
+		if err != nil {
+			t.Fatalf("ModulesByMaintainer(%q) error = %v", tt.user, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ModulesByMaintainer(%q) = %v, want %v", tt.user, got, tt.want)
+		}
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := client.ModulesByMaintainer(ctx, "alice"); !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+func TestSourcesForVersions(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		// /modules/testmod/<version>/source.json
+		version := strings.Split(r.URL.Path, "/")[3]
+		if version == "9.9.9" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&Source{URL: "https://example.com/testmod-" + version + ".tar.gz"})
+	}))
+	defer srv.Close()
+
+	versions := []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0", "9.9.9", "2.1.0"}
+	const limit = 2
+	c := New(WithBaseURL(srv.URL), WithMaxConcurrency(limit))
+	sources, err := c.SourcesForVersions(context.Background(), "testmod", versions)
+
+	if len(sources) != 5 {
+		t.Errorf("got %d sources, want 5", len(sources))
+	}
+	for version, src := range sources {
+		if want := "https://example.com/testmod-" + version + ".tar.gz"; src.URL != want {
+			t.Errorf("sources[%q].URL = %q, want %q", version, src.URL, want)
+		}
+	}
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || notFound.Version != "9.9.9" {
+		t.Errorf("error = %v, want *NotFoundError for 9.9.9", err)
+	}
+	if !strings.Contains(fmt.Sprint(err), "9.9.9: ") {
+		t.Errorf("error %q does not name the failed version", err)
+	}
+	if p := peak.Load(); p < 2 || p > limit {
+		t.Errorf("peak concurrency = %d, want %d", p, limit)
+	}
+}
+
+func TestListModulesWithMetadata(t *testing.T) {
+	dir := t.TempDir()
+	for mod, versions := range map[string]string{
+		"rules_go":     `["0.49.0","0.50.1"]`,
+		"rules_python": `["1.0.0"]`,
+		"zlib":         `["1.2.13","1.3","1.3.1"]`,
+	} {
+		modDir := filepath.Join(dir, "modules", mod)
+		if err := os.MkdirAll(modDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(modDir, "metadata.json"), []byte(`{"versions":`+versions+`}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	if err := NewFileRegistry(dir).WriteIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A module listed in the index whose metadata is corrupt.
+	index := filepath.Join(dir, "modules", "index.json")
+	if err := os.WriteFile(index, []byte(`["rules_go","rules_python","zlib","broken"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "modules", "broken"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "modules", "broken", "metadata.json"), []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	metas, err := New(WithBaseURL(srv.URL), WithMaxConcurrency(2)).ListModulesWithMetadata(ctx)
+	counts := map[string]int{}
+	for mod, meta := range metas {
+		counts[mod] = len(meta.Versions)
+	}
+	want := map[string]int{"rules_go": 2, "rules_python": 1, "zlib": 3}
+	if !maps.Equal(counts, want) {
+		t.Errorf("version counts = %v, want %v", counts, want)
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Module != "broken" {
+		t.Errorf("error = %v, want *ParseError for broken", err)
+	}
+}
+
+func TestListModulesPage(t *testing.T) {
+	modules := []string{"a", "b", "c", "d", "e"}
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/modules/index.json" {
+			requests.Add(1)
+			json.NewEncoder(w).Encode(modules)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		offset, limit int
+		want          []string
+	}{
+		{0, 2, []string{"a", "b"}},
+		{2, 2, []string{"c", "d"}},
+		{4, 2, []string{"e"}},
+		{5, 2, []string{}},
+		{10, 2, []string{}},
+		{1, 0, []string{"b", "c", "d", "e"}},
+	}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"cache", []Option{WithCache(NewMemoryCache())}},
+		{"no cache", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			requests.Store(0)
+			c := New(append(tc.opts, WithBaseURL(srv.URL))...)
+			for _, tt := range tests {
+				got, total, err := c.ListModulesPage(ctx, tt.offset, tt.limit)
+				if err != nil {
+					t.Fatalf("ListModulesPage(%d, %d) error = %v", tt.offset, tt.limit, err)
+				}
+				if !slices.Equal(got, tt.want) || total != len(modules) {
+					t.Errorf("ListModulesPage(%d, %d) = %v, %d; want %v, %d", tt.offset, tt.limit, got, total, tt.want, len(modules))
+				}
+				if len(got) > 0 {
+					got[0] = "changed"
+				}
+			}
+			if n := requests.Load(); n != 1 {
+				t.Errorf("index fetched %d times, want once", n)
+			}
+
+			if err := c.InvalidateAll(); err != nil {
+				t.Fatalf("InvalidateAll() error = %v", err)
+			}
+			if _, _, err := c.ListModulesPage(ctx, 0, 1); err != nil {
+				t.Fatalf("ListModulesPage() after InvalidateAll error = %v", err)
+			}
+			if n := requests.Load(); n != 2 {
+				t.Errorf("index fetched %d times after InvalidateAll, want twice", n)
+			}
+		})
+	}
+
+	c := New(WithBaseURL(srv.URL), WithCacheTTL(time.Nanosecond))
+	for range 2 {
+		if _, _, err := c.ListModulesPage(ctx, 0, 1); err != nil {
+			t.Fatalf("ListModulesPage() error = %v", err)
+		}
+	}
+	if n := requests.Load(); n != 4 {
+		t.Errorf("index fetched %d times in total, want the expired index refetched", n)
+	}
+
+	for _, page := range [][2]int{{-1, 2}, {0, -1}} {
+		if _, _, err := c.ListModulesPage(ctx, page[0], page[1]); err == nil {
+			t.Errorf("ListModulesPage(%d, %d) error = nil, want error", page[0], page[1])
+		}
+	}
+}
+
+func TestExistsBatch(t *testing.T) {
+	var heads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		}
+		module := strings.Split(r.URL.Path, "/")[2]
+		switch {
+		case strings.HasPrefix(module, "missing"):
+			http.NotFound(w, r)
+		case module == "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"versions":["1.0.0"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := New(WithBaseURL(srv.URL), WithMaxConcurrency(3))
+	ctx := context.Background()
+
+	modules := []string{"rules_go", "missing_a", "zlib", "missing_b", "protobuf"}
+	got, err := c.ExistsBatch(ctx, modules)
+	if err != nil {
+		t.Fatalf("ExistsBatch() error = %v", err)
+	}
+	want := map[string]bool{"rules_go": true, "missing_a": false, "zlib": true, "missing_b": false, "protobuf": true}
+	if !maps.Equal(got, want) {
+		t.Errorf("ExistsBatch() = %v, want %v", got, want)
+	}
+	if n := heads.Load(); n != int32(len(modules)) {
+		t.Errorf("sent %d HEAD requests, want %d", n, len(modules))
+	}
+
+	t.Run("other errors abort", func(t *testing.T) {
+		got, err := c.ExistsBatch(ctx, []string{"rules_go", "broken", "zlib"})
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("error = %v, want *RequestError with 500", err)
+		}
+		if !strings.HasPrefix(err.Error(), "broken: ") {
+			t.Errorf("error %q does not name the module", err)
+		}
+		if got != nil {
+			t.Errorf("ExistsBatch() = %v, want nil map on error", got)
+		}
+	})
+}
@@ -0,0 +1,613 @@
+package bcr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ModuleInfo holds the attributes of the module() call in a MODULE.bazel
+// file.
+type ModuleInfo struct {
+	// Name is the module name.
+	Name string
+
+	// Version is the module version, or empty if not declared.
+	Version string
+
+	// CompatibilityLevel is the module's compatibility level; versions
+	// with different levels are incompatible. Zero if not declared.
+	CompatibilityLevel int
+
+	// BazelCompatibility lists the Bazel versions the module works
+	// with (e.g., ">=7.0.0").
+	BazelCompatibility []string
+
+	// Deps lists the module's bazel_dep declarations, in file order, as
+	// returned by [ParseDeps].
+	Deps []Dep
+}
+
+// ParseModuleFile extracts the attributes of the module() call from
+// MODULE.bazel content, such as that returned by [Client.ModuleFile].
+//
+// Only literal attribute values are understood: strings, integers and
+// lists of strings. Other expressions are ignored, and a bazel_dep without
+// a literal name is left out of Deps. An error is returned if the content
+// cannot be tokenized, or if it does not contain exactly one module()
+// call.
+func ParseModuleFile(content []byte) (*ModuleInfo, error) {
+	calls, err := parseStarlarkCalls(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *ModuleInfo
+	var deps []Dep
+	for _, call := range calls {
+		if call.name == "bazel_dep" {
+			if dep := parseDep(call); dep.Name != "" {
+				deps = append(deps, dep)
+			}
+			continue
+		}
+		if call.name != "module" {
+			continue
+		}
+		if info != nil {
+			return nil, fmt.Errorf("bcr: failed to parse MODULE.bazel: line %d: module() called more than once", call.line)
+		}
+		info = &ModuleInfo{}
+		for _, arg := range call.resolve("name", "version", "compatibility_level", "repo_name", "bazel_compatibility") {
+			switch arg.name {
+			case "name":
+				info.Name, _ = arg.value.stringValue()
+			case "version":
+				info.Version, _ = arg.value.stringValue()
+			case "compatibility_level":
+				if n, ok := arg.value.intValue(); ok {
+					info.CompatibilityLevel = n
+				}
+			case "bazel_compatibility":
+				info.BazelCompatibility, _ = arg.value.stringList()
+			}
+		}
+	}
+	if info == nil {
+		return nil, errors.New("bcr: failed to parse MODULE.bazel: no module() call")
+	}
+	info.Deps = deps
+	return info, nil
+}
+
+// SatisfiesBazel reports whether the given Bazel version (e.g., "7.4.1")
+// meets every entry of m.BazelCompatibility. A module declaring no
+// entries works with any version.
+//
+// As in Bazel, each entry is a comparison such as ">=7.0.0" or "<8", or
+// a version to exclude prefixed with "-" (e.g., "-7.1.0"). An error is
+// returned if version or an entry is malformed.
+func (m *ModuleInfo) SatisfiesBazel(version string) (bool, error) {
+	if _, ok := parseVersion(version); !ok {
+		return false, fmt.Errorf("bcr: invalid Bazel version %q", version)
+	}
+	ok := true
+	for _, entry := range m.BazelCompatibility {
+		bounds, err := parseBazelCompatibility(entry)
+		if err != nil {
+			return false, err
+		}
+		for _, b := range bounds {
+			ok = ok && b.matches(version)
+		}
+	}
+	return ok, nil
+}
+
+// parseBazelCompatibility parses a bazel_compatibility entry. Unlike the
+// constraints of [Metadata.LatestMatching], an entry must start with one
+// of the operators Bazel accepts: ">=", "<=", ">", "<" or "-".
+func parseBazelCompatibility(entry string) ([]versionBound, error) {
+	if version, ok := strings.CutPrefix(entry, "-"); ok {
+		if _, ok := parseVersion(version); !ok {
+			return nil, fmt.Errorf("bcr: invalid bazel_compatibility entry %q", entry)
+		}
+		return []versionBound{{"!=", version}}, nil
+	}
+	if !strings.HasPrefix(entry, ">") && !strings.HasPrefix(entry, "<") || strings.ContainsAny(entry, " \t,") {
+		return nil, fmt.Errorf("bcr: invalid bazel_compatibility entry %q", entry)
+	}
+	bounds, err := parseConstraint(entry)
+	if err != nil {
+		return nil, fmt.Errorf("bcr: invalid bazel_compatibility entry %q", entry)
+	}
+	return bounds, nil
+}
+
+// Dep is a bazel_dep declaration in a MODULE.bazel file.
+type Dep struct {
+	// Name is the name of the module depended on.
+	Name string
+
+	// Version is the version depended on, or empty if not declared (for
+	// example, when an override provides the module).
+	Version string
+
+	// DevDependency reports whether the dependency is only used when
+	// the module is the root module (dev_dependency = True).
+	DevDependency bool
+}
+
+// ParseDepsOption configures [ParseDeps].
+type ParseDepsOption func(*parseDepsConfig)
+
+type parseDepsConfig struct {
+	excludeDev bool
+}
+
+// ExcludeDevDependencies makes [ParseDeps] skip dependencies declared
+// with dev_dependency = True, which are ignored when the module is not
+// the root module.
+func ExcludeDevDependencies() ParseDepsOption {
+	return func(c *parseDepsConfig) {
+		c.excludeDev = true
+	}
+}
+
+// ParseDeps returns the bazel_dep declarations in MODULE.bazel content, in
+// file order. Arguments may be passed by keyword or position.
+//
+// As with [ParseModuleFile], only literal values are understood. An error
+// is returned if the content cannot be tokenized or a bazel_dep has no
+// literal name.
+func ParseDeps(content []byte, opts ...ParseDepsOption) ([]Dep, error) {
+	var cfg parseDepsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	calls, err := parseStarlarkCalls(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []Dep
+	for _, call := range calls {
+		if call.name != "bazel_dep" {
+			continue
+		}
+		dep := parseDep(call)
+		if dep.Name == "" {
+			return nil, fmt.Errorf("bcr: failed to parse MODULE.bazel: line %d: bazel_dep without a literal name", call.line)
+		}
+		if cfg.excludeDev && dep.DevDependency {
+			continue
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// parseDep reads the literal arguments of a bazel_dep call. The name is
+// empty if it is not a literal.
+func parseDep(call starlarkCall) Dep {
+	var dep Dep
+	for _, arg := range call.resolve("name", "version", "max_compatibility_level", "repo_name", "dev_dependency") {
+		switch arg.name {
+		case "name":
+			dep.Name, _ = arg.value.stringValue()
+		case "version":
+			dep.Version, _ = arg.value.stringValue()
+		case "dev_dependency":
+			dep.DevDependency, _ = arg.value.boolValue()
+		}
+	}
+	return dep
+}
+
+// BazelDep is a module's requirement of a dependency, as merged by
+// [MergeDeps].
+type BazelDep struct {
+	// Version is the version required, or empty if not declared.
+	Version string
+
+	// Module is the name of the module declaring the requirement.
+	Module string
+
+	// DevDependency reports whether the requirement is declared with
+	// dev_dependency = True.
+	DevDependency bool
+}
+
+// MergeDeps combines the bazel_dep declarations of several modules, such
+// as those of a multi-module workspace, into a map from dependency name
+// to the requirements of it. A dependency required at several versions
+// has an entry for each, making conflicts visible.
+//
+// Requirements are sorted by version, then by requiring module, so the
+// result does not depend on the order of infos. An error is returned if
+// an info is nil or has no name.
+func MergeDeps(infos ...*ModuleInfo) (map[string][]BazelDep, error) {
+	merged := make(map[string][]BazelDep)
+	for _, info := range infos {
+		if info == nil || info.Name == "" {
+			return nil, errors.New("bcr: cannot merge deps: module without a name")
+		}
+		for _, dep := range info.Deps {
+			merged[dep.Name] = append(merged[dep.Name], BazelDep{Version: dep.Version, Module: info.Name, DevDependency: dep.DevDependency})
+		}
+	}
+	for _, deps := range merged {
+		slices.SortStableFunc(deps, func(a, b BazelDep) int {
+			return cmp.Or(
+				CompareVersions(a.Version, b.Version),
+				strings.Compare(a.Version, b.Version),
+				strings.Compare(a.Module, b.Module),
+			)
+		})
+	}
+	return merged, nil
+}
+
+// starlarkCall is a function call statement in a Starlark file, such as
+// module(...) or ext.tag(...).
+type starlarkCall struct {
+	name string // dotted callee name
+	args []callArg
+	line int
+}
+
+// callArg is a positional (empty name) or keyword argument of a call.
+type callArg struct {
+	name  string
+	value starlarkValue
+}
+
+// resolve returns the arguments of c with positional arguments named
+// after params, in order. Positional arguments beyond params are dropped.
+func (c starlarkCall) resolve(params ...string) []callArg {
+	args := make([]callArg, 0, len(c.args))
+	pos := 0
+	for _, arg := range c.args {
+		if arg.name == "" {
+			if pos >= len(params) {
+				continue
+			}
+			arg.name = params[pos]
+			pos++
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// starlarkValue is the value of a call argument. Only literals are
+// represented; any other expression has kind valueOther.
+type starlarkValue struct {
+	kind valueKind
+	str  string // valueString
+	num  int64  // valueInt
+	b    bool   // valueBool
+	list []starlarkValue
+}
+
+type valueKind int
+
+const (
+	valueOther valueKind = iota
+	valueString
+	valueInt
+	valueBool
+	valueList
+)
+
+func (v starlarkValue) stringValue() (string, bool) {
+	return v.str, v.kind == valueString
+}
+
+func (v starlarkValue) intValue() (int, bool) {
+	return int(v.num), v.kind == valueInt && int64(int(v.num)) == v.num
+}
+
+func (v starlarkValue) boolValue() (bool, bool) {
+	return v.b, v.kind == valueBool
+}
+
+func (v starlarkValue) stringList() ([]string, bool) {
+	if v.kind != valueList {
+		return nil, false
+	}
+	strs := make([]string, 0, len(v.list))
+	for _, elem := range v.list {
+		s, ok := elem.stringValue()
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, s)
+	}
+	return strs, true
+}
+
+// parseStarlarkCalls returns the top-level call statements of a Starlark
+// file such as MODULE.bazel, including calls whose result is assigned.
+func parseStarlarkCalls(content []byte) ([]starlarkCall, error) {
+	toks, err := tokenizeStarlark(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []starlarkCall
+	depth := 0
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		switch {
+		case tok.kind == tokPunct && strings.Contains("([{", tok.text):
+			depth++
+			continue
+		case tok.kind == tokPunct && strings.Contains(")]}", tok.text):
+			depth--
+			continue
+		case tok.kind != tokIdent || depth > 0:
+			continue
+		}
+		// Skip attribute accesses that continue an earlier expression.
+		if i > 0 && toks[i-1].isPunct(".") {
+			continue
+		}
+
+		// Read a dotted name: ident (. ident)*.
+		name := tok.text
+		j := i + 1
+		for j+1 < len(toks) && toks[j].isPunct(".") && toks[j+1].kind == tokIdent {
+			name += "." + toks[j+1].text
+			j += 2
+		}
+		if j >= len(toks) || !toks[j].isPunct("(") {
+			i = j - 1
+			continue
+		}
+
+		end := matchingClose(toks, j)
+		if end < 0 {
+			return nil, fmt.Errorf("bcr: failed to parse MODULE.bazel: line %d: unclosed call to %s", tok.line, name)
+		}
+		calls = append(calls, starlarkCall{
+			name: name,
+			args: parseCallArgs(toks[j+1 : end]),
+			line: tok.line,
+		})
+		i = end
+	}
+	if depth != 0 {
+		return nil, errors.New("bcr: failed to parse MODULE.bazel: unbalanced brackets")
+	}
+	return calls, nil
+}
+
+// matchingClose returns the index of the bracket closing toks[open], or
+// -1 if there is none.
+func matchingClose(toks []token, open int) int {
+	depth := 0
+	for i := open; i < len(toks); i++ {
+		switch {
+		case toks[i].kind != tokPunct:
+		case strings.Contains("([{", toks[i].text):
+			depth++
+		case strings.Contains(")]}", toks[i].text):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits toks at commas outside brackets, dropping empty
+// trailing elements.
+func splitTopLevel(toks []token) [][]token {
+	var parts [][]token
+	depth, start := 0, 0
+	for i, tok := range toks {
+		if tok.kind != tokPunct {
+			continue
+		}
+		switch {
+		case strings.Contains("([{", tok.text):
+			depth++
+		case strings.Contains(")]}", tok.text):
+			depth--
+		case tok.text == "," && depth == 0:
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(toks) {
+		parts = append(parts, toks[start:])
+	}
+	return parts
+}
+
+// parseCallArgs parses the tokens between a call's parentheses.
+func parseCallArgs(toks []token) []callArg {
+	var args []callArg
+	for _, part := range splitTopLevel(toks) {
+		if len(part) >= 2 && part[0].kind == tokIdent && part[1].isPunct("=") {
+			args = append(args, callArg{name: part[0].text, value: parseValue(part[2:])})
+			continue
+		}
+		args = append(args, callArg{value: parseValue(part)})
+	}
+	return args
+}
+
+// parseValue evaluates an argument expression if it is a literal.
+func parseValue(toks []token) starlarkValue {
+	switch {
+	case len(toks) == 1 && toks[0].kind == tokString:
+		return starlarkValue{kind: valueString, str: toks[0].text}
+	case len(toks) == 1 && toks[0].kind == tokInt:
+		if n, err := strconv.ParseInt(toks[0].text, 0, 64); err == nil {
+			return starlarkValue{kind: valueInt, num: n}
+		}
+	case len(toks) == 2 && toks[0].isPunct("-") && toks[1].kind == tokInt:
+		if n, err := strconv.ParseInt("-"+toks[1].text, 0, 64); err == nil {
+			return starlarkValue{kind: valueInt, num: n}
+		}
+	case len(toks) == 1 && toks[0].kind == tokIdent && (toks[0].text == "True" || toks[0].text == "False"):
+		return starlarkValue{kind: valueBool, b: toks[0].text == "True"}
+	case len(toks) >= 2 && toks[0].isPunct("[") && matchingClose(toks, 0) == len(toks)-1:
+		v := starlarkValue{kind: valueList}
+		for _, elem := range splitTopLevel(toks[1 : len(toks)-1]) {
+			v.list = append(v.list, parseValue(elem))
+		}
+		return v
+	}
+	return starlarkValue{}
+}
+
+// token is a lexical token of a Starlark file.
+type token struct {
+	kind tokenKind
+	text string // for strings, the decoded value
+	line int
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokInt
+	tokPunct
+)
+
+func (t token) isPunct(s string) bool {
+	return t.kind == tokPunct && t.text == s
+}
+
+// tokenizeStarlark splits Starlark source into tokens, dropping comments
+// and whitespace. It understands enough of the language to find calls and
+// literal arguments: identifiers, numbers, string literals (including raw
+// and triple-quoted strings) and punctuation.
+func tokenizeStarlark(src string) ([]token, error) {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\\':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'' || ((c == 'r' || c == 'R') && i+1 < len(src) && (src[i+1] == '"' || src[i+1] == '\'')):
+			raw := c == 'r' || c == 'R'
+			if raw {
+				i++
+			}
+			start := line
+			s, n, lines, err := scanString(src[i:], raw)
+			if err != nil {
+				return nil, fmt.Errorf("bcr: failed to parse MODULE.bazel: line %d: %w", start, err)
+			}
+			toks = append(toks, token{kind: tokString, text: s, line: start})
+			i += n
+			line += lines
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && (isIdentStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[i:j], line: line})
+			i = j
+		case isDigit(c):
+			j := i + 1
+			for j < len(src) && (isIdentStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{kind: tokInt, text: src[i:j], line: line})
+			i = j
+		default:
+			n := 1
+			if i+1 < len(src) && src[i+1] == '=' && strings.IndexByte("=!<>+-*/%", c) >= 0 {
+				n = 2
+			}
+			toks = append(toks, token{kind: tokPunct, text: src[i : i+n], line: line})
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// scanString decodes the string literal at the start of s, which begins
+// with its opening quote. It returns the value, the number of bytes
+// consumed and the number of newlines they contain.
+func scanString(s string, raw bool) (value string, n, lines int, err error) {
+	quote := s[:1]
+	if strings.HasPrefix(s, strings.Repeat(quote, 3)) {
+		quote = s[:3]
+	}
+	triple := len(quote) == 3
+
+	var b strings.Builder
+	for i := len(quote); i < len(s); {
+		c := s[i]
+		switch {
+		case strings.HasPrefix(s[i:], quote):
+			return b.String(), i + len(quote), lines, nil
+		case c == '\n':
+			if !triple {
+				return "", 0, 0, errors.New("unterminated string literal")
+			}
+			lines++
+			b.WriteByte(c)
+			i++
+		case c == '\\' && i+1 < len(s):
+			next := s[i+1]
+			if next == '\n' {
+				lines++
+			}
+			if raw {
+				b.WriteByte(c)
+				b.WriteByte(next)
+			} else {
+				b.WriteString(unescape(next))
+			}
+			i += 2
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, 0, errors.New("unterminated string literal")
+}
+
+// unescape returns the text of the escape sequence \c.
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case '\n':
+		return "" // line continuation
+	case '\\', '\'', '"':
+		return string(c)
+	default:
+		return "\\" + string(c)
+	}
+}
@@ -0,0 +1,296 @@
+package bcr
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseModuleFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    ModuleInfo
+	}{
+		{
+			name: "rules_go",
+			content: `module(
+    name = "rules_go",
+    version = "0.50.1",
+    compatibility_level = 0,
+    repo_name = "io_bazel_rules_go",
+)
+
+bazel_dep(name = "bazel_features", version = "1.9.1")
+bazel_dep(name = "bazel_skylib", version = "1.2.0")
+
+go_sdk = use_extension("//go:extensions.bzl", "go_sdk")
+go_sdk.download(name = "go_default_sdk", version = "1.21.1")
+use_repo(go_sdk, "go_toolchains")
+`,
+			want: ModuleInfo{Name: "rules_go", Version: "0.50.1"},
+		},
+		{
+			name: "comments and bazel_compatibility",
+			content: `# Protocol Buffers
+module(
+    name = "protobuf",  # the module name
+    version = "29.0",
+    # compatibility_level = 99,
+    compatibility_level = 1,
+    bazel_compatibility = [">=7.0.0", "<9.0.0"],
+)
+`,
+			want: ModuleInfo{Name: "protobuf", Version: "29.0", CompatibilityLevel: 1, BazelCompatibility: []string{">=7.0.0", "<9.0.0"}},
+		},
+		{
+			name:    "single line single quotes",
+			content: `module(name='abseil-cpp', version='20240722.0', compatibility_level=1)`,
+			want:    ModuleInfo{Name: "abseil-cpp", Version: "20240722.0", CompatibilityLevel: 1},
+		},
+		{
+			name:    "positional arguments",
+			content: `module("zlib", "1.3.1.bcr.3", 2)`,
+			want:    ModuleInfo{Name: "zlib", Version: "1.3.1.bcr.3", CompatibilityLevel: 2},
+		},
+		{
+			name: "strings containing syntax",
+			content: `"""Docstring with module(name = "fake") inside."""
+
+module(
+    name = "tricky",
+    version = "1.0.0",
+)
+
+bazel_dep(name = "dep", version = "2.0 # not a comment")
+`,
+			want: ModuleInfo{Name: "tricky", Version: "1.0.0"},
+		},
+		{
+			name:    "no version",
+			content: `module(name = "root")`,
+			want:    ModuleInfo{Name: "root"},
+		},
+		{
+			name:    "non-literal value ignored",
+			content: `module(name = "computed", version = VERSION, compatibility_level = LEVEL + 1)`,
+			want:    ModuleInfo{Name: "computed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseModuleFile([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("ParseModuleFile() error = %v", err)
+			}
+			if got.Name != tt.want.Name || got.Version != tt.want.Version || got.CompatibilityLevel != tt.want.CompatibilityLevel {
+				t.Errorf("ParseModuleFile() = %+v, want %+v", got, tt.want)
+			}
+			if !slices.Equal(got.BazelCompatibility, tt.want.BazelCompatibility) {
+				t.Errorf("BazelCompatibility = %q, want %q", got.BazelCompatibility, tt.want.BazelCompatibility)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"no module call":      `bazel_dep(name = "foo", version = "1.0")`,
+			"empty":               ``,
+			"two module calls":    "module(name = \"a\")\nmodule(name = \"b\")",
+			"unterminated string": `module(name = "broken)`,
+			"unclosed call":       `module(name = "broken"`,
+		} {
+			if _, err := ParseModuleFile([]byte(content)); err == nil {
+				t.Errorf("%s: ParseModuleFile() should fail", name)
+			}
+		}
+	})
+}
+
+func TestSatisfiesBazel(t *testing.T) {
+	tests := []struct {
+		constraints []string
+		version     string
+		want        bool
+	}{
+		{nil, "6.0.0", true},
+		{[]string{">=7.0.0"}, "7.0.0", true},
+		{[]string{">=7.0.0"}, "6.5.0", false},
+		{[]string{">=7.0.0", "<8"}, "7.4.1", true},
+		{[]string{">=7.0.0", "<8"}, "8.0.0", false},
+		{[]string{">=7.0.0", "<8"}, "8.0.0-rc1", false},
+		{[]string{"<=7.1.0"}, "7.1.0", true},
+		{[]string{">7.1.0"}, "7.1.0", false},
+		{[]string{">=7.0.0", "-7.1.0"}, "7.1.0", false},
+		{[]string{">=7.0.0", "-7.1.0"}, "7.1.1", true},
+	}
+	for _, tt := range tests {
+		info := &ModuleInfo{BazelCompatibility: tt.constraints}
+		got, err := info.SatisfiesBazel(tt.version)
+		if err != nil || got != tt.want {
+			t.Errorf("SatisfiesBazel(%q) with %q = %v, %v; want %v", tt.version, tt.constraints, got, err, tt.want)
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			constraints []string
+			version     string
+		}{
+			{[]string{">=7.0.0"}, "latest"},
+			{[]string{"7.0.0"}, "7.0.0"},
+			{[]string{"~7.0"}, "7.0.0"},
+			{[]string{">=7.0.0 <8"}, "7.0.0"},
+			{[]string{">=seven"}, "7.0.0"},
+			{[]string{"-"}, "7.0.0"},
+		} {
+			info := &ModuleInfo{BazelCompatibility: tt.constraints}
+			if _, err := info.SatisfiesBazel(tt.version); err == nil {
+				t.Errorf("SatisfiesBazel(%q) with %q should fail", tt.version, tt.constraints)
+			}
+		}
+	})
+}
+
+func TestTokenizeStarlark(t *testing.T) {
+	toks, err := tokenizeStarlark("x = r'a\\.b' + \"\"\"multi\nline\"\"\" # c\ny == -1")
+	if err != nil {
+		t.Fatalf("tokenizeStarlark() error = %v", err)
+	}
+	var got []string
+	for _, tok := range toks {
+		got = append(got, tok.text)
+	}
+	want := []string{"x", "=", `a\.b`, "+", "multi\nline", "y", "==", "-", "1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("tokens = %q, want %q", got, want)
+	}
+	if line := toks[len(toks)-1].line; line != 3 {
+		t.Errorf("last token line = %d, want 3", line)
+	}
+}
+
+func TestParseDeps(t *testing.T) {
+	content := []byte(`module(name = "rules_go", version = "0.50.1")
+
+bazel_dep(name = "bazel_features", version = "1.9.1")
+bazel_dep(
+    name = "bazel_skylib",
+    version = "1.2.0",
+    repo_name = "skylib",
+)
+bazel_dep("platforms", "0.0.10")
+bazel_dep(name = "gazelle", version = "0.36.0", dev_dependency = True)
+bazel_dep("rules_testing", "0.6.0", -1, "", True)
+bazel_dep(name = "rules_proto", dev_dependency = False)  # version from override
+
+# bazel_dep(name = "commented_out", version = "1.0")
+local_path_override(module_name = "rules_proto", path = "../rules_proto")
+`)
+
+	t.Run("all", func(t *testing.T) {
+		got, err := ParseDeps(content)
+		if err != nil {
+			t.Fatalf("ParseDeps() error = %v", err)
+		}
+		want := []Dep{
+			{Name: "bazel_features", Version: "1.9.1"},
+			{Name: "bazel_skylib", Version: "1.2.0"},
+			{Name: "platforms", Version: "0.0.10"},
+			{Name: "gazelle", Version: "0.36.0", DevDependency: true},
+			{Name: "rules_testing", Version: "0.6.0", DevDependency: true},
+			{Name: "rules_proto"},
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("ParseDeps() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("exclude dev dependencies", func(t *testing.T) {
+		got, err := ParseDeps(content, ExcludeDevDependencies())
+		if err != nil {
+			t.Fatalf("ParseDeps() error = %v", err)
+		}
+		var names []string
+		for _, dep := range got {
+			names = append(names, dep.Name)
+		}
+		if want := []string{"bazel_features", "bazel_skylib", "platforms", "rules_proto"}; !slices.Equal(names, want) {
+			t.Errorf("ParseDeps() names = %q, want %q", names, want)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		got, err := ParseDeps([]byte(`module(name = "leaf")`))
+		if err != nil || len(got) != 0 {
+			t.Errorf("ParseDeps() = %+v, %v, want no deps", got, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, content := range []string{`bazel_dep(version = "1.0")`, `bazel_dep(name = NAME)`, `bazel_dep(name = "x`} {
+			if _, err := ParseDeps([]byte(content)); err == nil {
+				t.Errorf("ParseDeps(%q) should fail", content)
+			}
+		}
+	})
+}
+
+func TestMergeDeps(t *testing.T) {
+	parse := func(content string) *ModuleInfo {
+		t.Helper()
+		info, err := ParseModuleFile([]byte(content))
+		if err != nil {
+			t.Fatalf("ParseModuleFile() error = %v", err)
+		}
+		return info
+	}
+	app := parse(`module(name = "app")
+bazel_dep(name = "rules_go", version = "0.50.1")
+bazel_dep(name = "protobuf", version = "29.0")
+bazel_dep(name = NAME, version = "1.0")
+`)
+	lib := parse(`module(name = "lib")
+bazel_dep(name = "protobuf", version = "21.7")
+bazel_dep(name = "gazelle", version = "0.36.0", dev_dependency = True)
+`)
+	tool := parse(`module(name = "tool")
+bazel_dep(name = "protobuf", version = "29.0")
+`)
+
+	wantAppDeps := []Dep{{Name: "rules_go", Version: "0.50.1"}, {Name: "protobuf", Version: "29.0"}}
+	if !slices.Equal(app.Deps, wantAppDeps) {
+		t.Errorf("app Deps = %+v, want %+v", app.Deps, wantAppDeps)
+	}
+
+	want := map[string][]BazelDep{
+		"protobuf": {
+			{Version: "21.7", Module: "lib"},
+			{Version: "29.0", Module: "app"},
+			{Version: "29.0", Module: "tool"},
+		},
+		"rules_go": {{Version: "0.50.1", Module: "app"}},
+		"gazelle":  {{Version: "0.36.0", Module: "lib", DevDependency: true}},
+	}
+	for _, infos := range [][]*ModuleInfo{{app, lib, tool}, {tool, lib, app}} {
+		got, err := MergeDeps(infos...)
+		if err != nil {
+			t.Fatalf("MergeDeps() error = %v", err)
+		}
+		if len(got) != len(want) {
+			t.Errorf("MergeDeps() = %+v, want %+v", got, want)
+		}
+		for name, deps := range want {
+			if !slices.Equal(got[name], deps) {
+				t.Errorf("MergeDeps()[%q] = %+v, want %+v", name, got[name], deps)
+			}
+		}
+	}
+
+	if _, err := MergeDeps(app, nil); err == nil {
+		t.Error("MergeDeps() with a nil info should fail")
+	}
+	if _, err := MergeDeps(&ModuleInfo{Deps: wantAppDeps}); err == nil {
+		t.Error("MergeDeps() with an unnamed module should fail")
+	}
+}
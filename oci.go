@@ -0,0 +1,313 @@
+package bcr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// OCIRegistry is a Registry whose files are stored as OCI artifacts in
+// a container registry, such as ghcr.io.
+//
+// Each module is a repository under the configured one, holding one
+// artifact per version and one for the module metadata:
+//
+//	<repository>/<module>:metadata    layer titled metadata.json
+//	<repository>/<module>:<version>   layers titled source.json, MODULE.bazel
+//
+// Layers are matched by their org.opencontainers.image.title annotation.
+// OCI tags cannot contain "+", so it is replaced by "_" in version tags,
+// as Helm does for chart versions.
+//
+// Anonymous bearer token authentication, as used by public repositories
+// on most registries, is handled automatically.
+type OCIRegistry struct {
+	base string // scheme and host, e.g. "https://ghcr.io"
+	repo string // repository path, e.g. "org/bcr"
+	http *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]string // bearer token by repository
+}
+
+// ociMetadataTag is the tag of the artifact holding a module's metadata.
+const ociMetadataTag = "metadata"
+
+// ociTitleAnnotation names the file a layer holds.
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+// ociManifestTypes are the manifest media types accepted from the registry.
+var ociManifestTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// NewOCIRegistry creates a registry reading from the given OCI
+// repository, such as "ghcr.io/org/bcr". The scheme defaults to https;
+// include it to use another, e.g. "http://localhost:5000/bcr". If client
+// is nil, [http.DefaultClient] is used.
+func NewOCIRegistry(repository string, client *http.Client) (*OCIRegistry, error) {
+	ref := repository
+	if !strings.Contains(ref, "://") {
+		ref = "https://" + ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("bcr: invalid OCI repository %q: %w", repository, err)
+	}
+	repo := strings.Trim(u.Path, "/")
+	if u.Host == "" || repo == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("bcr: invalid OCI repository %q: want host/path", repository)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &OCIRegistry{
+		base:   u.Scheme + "://" + u.Host,
+		repo:   repo,
+		http:   client,
+		tokens: make(map[string]string),
+	}, nil
+}
+
+// Metadata fetches module metadata from the module's metadata artifact.
+func (r *OCIRegistry) Metadata(ctx context.Context, module string) (*Metadata, error) {
+	data, err := r.file(ctx, module, "", "metadata.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, &ParseError{Module: module, File: "metadata.json", Err: err}
+	}
+	return &meta, nil
+}
+
+// Source fetches source information from the version's artifact.
+func (r *OCIRegistry) Source(ctx context.Context, module, version string) (*Source, error) {
+	data, err := r.file(ctx, module, version, "source.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var src Source
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, &ParseError{Module: module, Version: version, File: "source.json", Err: err}
+	}
+	return &src, nil
+}
+
+// ModuleFile fetches MODULE.bazel from the version's artifact.
+func (r *OCIRegistry) ModuleFile(ctx context.Context, module, version string) ([]byte, error) {
+	return r.file(ctx, module, version, "MODULE.bazel")
+}
+
+// String returns the repository reference, e.g. "oci://ghcr.io/org/bcr".
+func (r *OCIRegistry) String() string {
+	_, host, _ := strings.Cut(r.base, "://")
+	return "oci://" + host + "/" + r.repo
+}
+
+// Type returns the registry type ("oci").
+func (r *OCIRegistry) Type() string {
+	return "oci"
+}
+
+// ociManifest is the part of an OCI image manifest needed to find files.
+type ociManifest struct {
+	Layers []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// file fetches the layer titled name from the artifact of a module
+// version, or of the module's metadata if version is empty.
+func (r *OCIRegistry) file(ctx context.Context, module, version, name string) ([]byte, error) {
+	notFound := &NotFoundError{Module: module, Version: version}
+	if !isPathSegment(module) || (version != "" && !isPathSegment(version)) {
+		return nil, notFound
+	}
+	tag := ociMetadataTag
+	if version != "" {
+		tag = strings.ReplaceAll(version, "+", "_")
+	}
+	repo := r.repo + "/" + module
+
+	data, err := r.get(ctx, repo, "manifests/"+tag, strings.Join(ociManifestTypes, ", "), notFound)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, &ParseError{Module: module, Version: version, File: "manifest", Err: err}
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.Annotations[ociTitleAnnotation] != name {
+			continue
+		}
+		algo, want, ok := strings.Cut(layer.Digest, ":")
+		if !ok || algo != "sha256" || !isPathSegment(layer.Digest) {
+			return nil, fmt.Errorf("bcr: unsupported digest %q for %s in %s:%s", layer.Digest, name, repo, tag)
+		}
+		blob, err := r.get(ctx, repo, "blobs/"+layer.Digest, "", notFound)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(blob)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			return nil, &IntegrityError{Expected: layer.Digest, Actual: algo + ":" + got}
+		}
+		return blob, nil
+	}
+	return nil, notFound
+}
+
+// get fetches /v2/<repo>/<resource>, returning notFound for a 404. If the
+// registry asks for a bearer token, one is requested and the fetch is
+// retried once.
+func (r *OCIRegistry) get(ctx context.Context, repo, resource, accept string, notFound error) ([]byte, error) {
+	u := r.base + "/v2/" + repo + "/" + resource
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("bcr: failed to create request: %w", err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		r.mu.Lock()
+		token := r.tokens[repo]
+		r.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := r.http.Do(req)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, &RequestError{URL: u, Err: ctxErr}
+			}
+			if isConnError(err) {
+				return nil, &RegistryUnavailableError{URL: u, Err: err}
+			}
+			return nil, &RequestError{URL: u, Err: err}
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			token, tokenErr := r.fetchToken(ctx, challenge)
+			if tokenErr != nil {
+				return nil, &RequestError{URL: u, StatusCode: resp.StatusCode, Err: tokenErr}
+			}
+			r.mu.Lock()
+			r.tokens[repo] = token
+			r.mu.Unlock()
+			continue
+		case resp.StatusCode == http.StatusNotFound:
+			return nil, notFound
+		case resp.StatusCode != http.StatusOK:
+			return nil, &RequestError{URL: u, StatusCode: resp.StatusCode}
+		case err != nil:
+			return nil, &RequestError{URL: u, Err: fmt.Errorf("failed to read response: %w", err)}
+		}
+		return data, nil
+	}
+}
+
+// fetchToken requests an anonymous bearer token as described by a
+// WWW-Authenticate challenge such as
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:org/bcr:pull"`.
+func (r *OCIRegistry) fetchToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	attrs := parseChallengeParams(params)
+	realm := attrs["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q has no realm", challenge)
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", realm, err)
+	}
+	q := u.Query()
+	for _, key := range []string{"service", "scope"} {
+		if v := attrs[key]; v != "" {
+			q.Set(key, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("token response has no token")
+}
+
+// parseChallengeParams parses the comma-separated key="value" parameters
+// of a WWW-Authenticate challenge. Quoted values may contain commas.
+func parseChallengeParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:1+end], rest[2+end:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+		s = strings.TrimLeft(rest, ", ")
+	}
+	return params
+}
+
+// Ensure OCIRegistry implements Registry at compile time.
+var _ Registry = (*OCIRegistry)(nil)
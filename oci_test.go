@@ -0,0 +1,245 @@
+package bcr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ociMock is an in-memory OCI distribution registry serving artifacts
+// whose layers are the given files.
+type ociMock struct {
+	manifests map[string][]byte // by "<repo>:<tag>"
+	blobs     map[string][]byte // by digest
+	token     string            // if set, required as a bearer token
+}
+
+func newOCIMock() *ociMock {
+	return &ociMock{manifests: make(map[string][]byte), blobs: make(map[string][]byte)}
+}
+
+// push stores an artifact with one layer per file.
+func (m *ociMock) push(repo, tag string, files map[string]string) {
+	type layer struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Size        int               `json:"size"`
+		Annotations map[string]string `json:"annotations"`
+	}
+	var layers []layer
+	for name, content := range files {
+		sum := sha256.Sum256([]byte(content))
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		m.blobs[digest] = []byte(content)
+		layers = append(layers, layer{
+			MediaType:   "application/octet-stream",
+			Digest:      digest,
+			Size:        len(content),
+			Annotations: map[string]string{ociTitleAnnotation: name},
+		})
+	}
+	manifest, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        layers,
+	})
+	m.manifests[repo+":"+tag] = manifest
+}
+
+func (m *ociMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		json.NewEncoder(w).Encode(map[string]string{"token": m.token})
+		return
+	}
+	if m.token != "" && r.Header.Get("Authorization") != "Bearer "+m.token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="mock",scope="repository:bcr:pull"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v2/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if i := strings.LastIndex(rest, "/manifests/"); i >= 0 {
+		manifest, ok := m.manifests[rest[:i]+":"+rest[i+len("/manifests/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		w.Write(manifest)
+		return
+	}
+	if i := strings.LastIndex(rest, "/blobs/"); i >= 0 {
+		blob, ok := m.blobs[rest[i+len("/blobs/"):]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(blob)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func TestOCIRegistry(t *testing.T) {
+	mock := newOCIMock()
+	mock.push("bcr/rules_go", "metadata", map[string]string{
+		"metadata.json": `{"versions":["0.50.1","1.0.0+build.1"]}`,
+	})
+	mock.push("bcr/rules_go", "0.50.1", map[string]string{
+		"source.json":  `{"url":"https://example.com/rules_go.zip","integrity":"sha256-abc"}`,
+		"MODULE.bazel": `module(name = "rules_go", version = "0.50.1")`,
+	})
+	mock.push("bcr/rules_go", "1.0.0_build.1", map[string]string{
+		"MODULE.bazel": `module(name = "rules_go", version = "1.0.0+build.1")`,
+	})
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	reg, err := NewOCIRegistry(srv.URL+"/bcr", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	meta, err := reg.Metadata(ctx, "rules_go")
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if len(meta.Versions) != 2 {
+		t.Errorf("Versions = %v", meta.Versions)
+	}
+
+	src, err := reg.Source(ctx, "rules_go", "0.50.1")
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+	if src.URL != "https://example.com/rules_go.zip" {
+		t.Errorf("URL = %q", src.URL)
+	}
+
+	data, err := reg.ModuleFile(ctx, "rules_go", "1.0.0+build.1")
+	if err != nil {
+		t.Fatalf("ModuleFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), "1.0.0+build.1") {
+		t.Errorf("ModuleFile() = %q", data)
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		tests := map[string]func() error{
+			"missing module": func() error {
+				_, err := reg.Metadata(ctx, "nonexistent")
+				return err
+			},
+			"missing version": func() error {
+				_, err := reg.Source(ctx, "rules_go", "9.9.9")
+				return err
+			},
+			"missing layer": func() error {
+				_, err := reg.Source(ctx, "rules_go", "1.0.0+build.1")
+				return err
+			},
+			"invalid module": func() error {
+				_, err := reg.Metadata(ctx, "../etc")
+				return err
+			},
+		}
+		for name, call := range tests {
+			var notFound *NotFoundError
+			if err := call(); !errors.As(err, &notFound) {
+				t.Errorf("%s: error = %v, want *NotFoundError", name, err)
+			}
+		}
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		for digest := range mock.blobs {
+			if string(mock.blobs[digest]) == `{"versions":["0.50.1","1.0.0+build.1"]}` {
+				mock.blobs[digest] = []byte(`{"versions":["6.6.6"]}`)
+			}
+		}
+		var integrityErr *IntegrityError
+		if _, err := reg.Metadata(ctx, "rules_go"); !errors.As(err, &integrityErr) {
+			t.Errorf("error = %v, want *IntegrityError", err)
+		}
+	})
+}
+
+func TestOCIRegistryTokenAuth(t *testing.T) {
+	mock := newOCIMock()
+	mock.token = "secret"
+	mock.push("bcr/zlib", "metadata", map[string]string{"metadata.json": `{"versions":["1.3.1"]}`})
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	reg, err := NewOCIRegistry(srv.URL+"/bcr", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := reg.Metadata(context.Background(), "zlib")
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if !meta.HasVersion("1.3.1") {
+		t.Errorf("Versions = %v", meta.Versions)
+	}
+}
+
+func TestNewOCIRegistry(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"ghcr.io/org/bcr", "oci://ghcr.io/org/bcr", false},
+		{"http://localhost:5000/bcr/", "oci://localhost:5000/bcr", false},
+		{"ghcr.io", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		reg, err := NewOCIRegistry(tt.ref, nil)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewOCIRegistry(%q) error = nil, want error", tt.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewOCIRegistry(%q) error = %v", tt.ref, err)
+			continue
+		}
+		if got := reg.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+		if reg.Type() != "oci" {
+			t.Errorf("Type() = %q, want oci", reg.Type())
+		}
+	}
+}
+
+func TestParseChallengeParams(t *testing.T) {
+	got := parseChallengeParams(`realm="https://auth.example/token",service=registry.example, scope="repository:a/b:pull,push"`)
+	want := map[string]string{
+		"realm":   "https://auth.example/token",
+		"service": "registry.example",
+		"scope":   "repository:a/b:pull,push",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("params[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d params, want %d: %v", len(got), len(want), got)
+	}
+}
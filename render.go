@@ -0,0 +1,155 @@
+package bcr
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RenderHTTPArchive renders the source as an http_archive repository rule
+// named name, for use in WORKSPACE-style files.
+//
+// The rule downloads from [Source.AllURLs] and is verified with the
+// source's integrity. Patches are listed by file name in sorted order, the
+// order the registry applies them in; callers must make them available
+// under those names (e.g., by rewriting them to labels). An error is
+// returned for sources that are not archives or have no URL.
+func (s *Source) RenderHTTPArchive(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("bcr: http_archive needs a name")
+	}
+	switch t := s.SourceType(); t {
+	case "archive":
+	case "git_repository":
+		return "", errors.New("bcr: cannot render a git_repository source as http_archive; use RenderGitRepository")
+	default:
+		return "", fmt.Errorf("bcr: cannot render a %s source as http_archive", t)
+	}
+	urls := s.AllURLs()
+	if len(urls) == 0 {
+		return "", errors.New("bcr: cannot render http_archive: source has no URL")
+	}
+
+	r := newRuleWriter("http_archive", name)
+	r.list("urls", urls)
+	r.str("integrity", s.Integrity)
+	r.str("strip_prefix", s.StripPrefix)
+	r.str("type", s.ArchiveType)
+	r.patches(s)
+	return r.String(), nil
+}
+
+// RenderGitRepository renders the source as a git_repository repository
+// rule named name, for use in WORKSPACE-style files.
+//
+// The rule fetches Commit from Remote, with shallow_since when the source
+// sets it. Patches are rendered as with [Source.RenderHTTPArchive]. An
+// error is returned for sources that are not git repositories or have no
+// remote or commit.
+func (s *Source) RenderGitRepository(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("bcr: git_repository needs a name")
+	}
+	if t := s.SourceType(); t != "git_repository" {
+		return "", fmt.Errorf("bcr: cannot render a %s source as git_repository", t)
+	}
+	if s.Remote == "" || s.Commit == "" {
+		return "", errors.New("bcr: cannot render git_repository: source needs a remote and a commit")
+	}
+
+	r := newRuleWriter("git_repository", name)
+	r.str("remote", s.Remote)
+	r.str("commit", s.Commit)
+	r.str("shallow_since", s.ShallowSince)
+	r.str("strip_prefix", s.StripPrefix)
+	r.patches(s)
+	return r.String(), nil
+}
+
+// ruleWriter renders a Starlark repository rule call with one attribute
+// per line.
+type ruleWriter struct {
+	b strings.Builder
+}
+
+func newRuleWriter(rule, name string) *ruleWriter {
+	r := &ruleWriter{}
+	r.b.WriteString(rule + "(\n")
+	r.str("name", name)
+	return r
+}
+
+// str writes a string attribute, omitting it if value is empty.
+func (r *ruleWriter) str(attr, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(&r.b, "    %s = %s,\n", attr, starlarkQuote(value))
+}
+
+// list writes a list of strings attribute, omitting it if empty.
+func (r *ruleWriter) list(attr string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = starlarkQuote(v)
+	}
+	if len(values) == 1 {
+		fmt.Fprintf(&r.b, "    %s = [%s],\n", attr, quoted[0])
+		return
+	}
+	fmt.Fprintf(&r.b, "    %s = [\n", attr)
+	for _, q := range quoted {
+		fmt.Fprintf(&r.b, "        %s,\n", q)
+	}
+	r.b.WriteString("    ],\n")
+}
+
+// patches writes the patches of s in sorted order, with their strip level.
+func (r *ruleWriter) patches(s *Source) {
+	if len(s.Patches) == 0 {
+		return
+	}
+	r.list("patches", slices.Sorted(maps.Keys(s.Patches)))
+	if s.PatchStrip > 0 {
+		r.list("patch_args", []string{"-p" + strconv.Itoa(s.PatchStrip)})
+	}
+}
+
+func (r *ruleWriter) String() string {
+	return r.b.String() + ")\n"
+}
+
+// starlarkQuote returns s as a double-quoted Starlark string literal.
+// Control characters are written as octal escapes, which Bazel accepts;
+// other characters, including non-ASCII ones, are written as is.
+func starlarkQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\%03o`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
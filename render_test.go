@@ -0,0 +1,173 @@
+package bcr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTTPArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		src  *Source
+		want string
+	}{
+		{
+			name: "minimal",
+			src: &Source{
+				URL:       "https://github.com/madler/zlib/releases/download/v1.3.1/zlib-1.3.1.tar.gz",
+				Integrity: "sha256-mpOyt9/ax3zrpaVYpYDnRmfdb+3kWFuR7vtg8Dty3yM=",
+			},
+			want: `http_archive(
+    name = "zlib",
+    urls = ["https://github.com/madler/zlib/releases/download/v1.3.1/zlib-1.3.1.tar.gz"],
+    integrity = "sha256-mpOyt9/ax3zrpaVYpYDnRmfdb+3kWFuR7vtg8Dty3yM=",
+)
+`,
+		},
+		{
+			name: "full",
+			src: &Source{
+				URL:         "https://example.com/zlib.zip",
+				URLs:        []string{"https://mirror.example.com/zlib.zip", "https://example.com/zlib.zip"},
+				Integrity:   "sha256-abc=",
+				StripPrefix: "zlib-1.3.1",
+				ArchiveType: "zip",
+				Patches: map[string]string{
+					"z_add_build_file.patch": "sha256-def=",
+					"a_fix_visibility.patch": "sha256-ghi=",
+				},
+				PatchStrip: 1,
+			},
+			want: `http_archive(
+    name = "zlib",
+    urls = [
+        "https://mirror.example.com/zlib.zip",
+        "https://example.com/zlib.zip",
+    ],
+    integrity = "sha256-abc=",
+    strip_prefix = "zlib-1.3.1",
+    type = "zip",
+    patches = [
+        "a_fix_visibility.patch",
+        "z_add_build_file.patch",
+    ],
+    patch_args = ["-p1"],
+)
+`,
+		},
+		{
+			name: "escaping",
+			src: &Source{
+				URL:         `https://example.com/a"b\c.tar.gz`,
+				StripPrefix: "dir\nwith\x01ctrl",
+			},
+			want: `http_archive(
+    name = "zlib",
+    urls = ["https://example.com/a\"b\\c.tar.gz"],
+    strip_prefix = "dir\nwith\001ctrl",
+)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.src.RenderHTTPArchive("zlib")
+			if err != nil {
+				t.Fatalf("RenderHTTPArchive() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderHTTPArchive() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := (&Source{Type: "git_repository", Remote: "https://github.com/owner/repo.git"}).RenderHTTPArchive("repo")
+		if err == nil || !strings.Contains(err.Error(), "RenderGitRepository") {
+			t.Errorf("git source: error = %v, want it to suggest RenderGitRepository", err)
+		}
+		for name, src := range map[string]*Source{
+			"local_path": {Type: "local_path", Path: "../mod"},
+			"no url":     {Integrity: "sha256-abc="},
+			"nil":        nil,
+		} {
+			if _, err := src.RenderHTTPArchive("repo"); err == nil {
+				t.Errorf("%s: RenderHTTPArchive() should fail", name)
+			}
+		}
+		if _, err := (&Source{URL: "https://example.com/a.zip"}).RenderHTTPArchive(""); err == nil {
+			t.Error("empty name: RenderHTTPArchive() should fail")
+		}
+	})
+}
+
+func TestRenderGitRepository(t *testing.T) {
+	tests := []struct {
+		name string
+		src  *Source
+		want string
+	}{
+		{
+			name: "shallow since",
+			src: &Source{
+				Type:         "git_repository",
+				Remote:       "https://github.com/bazelbuild/rules_cc.git",
+				Commit:       "b1c40e1de81913a3c40e5948f78719c28152486d",
+				ShallowSince: "1605101351 -0800",
+			},
+			want: `git_repository(
+    name = "rules_cc",
+    remote = "https://github.com/bazelbuild/rules_cc.git",
+    commit = "b1c40e1de81913a3c40e5948f78719c28152486d",
+    shallow_since = "1605101351 -0800",
+)
+`,
+		},
+		{
+			name: "without shallow since",
+			src: &Source{
+				Type:        "git_repository",
+				Remote:      "https://github.com/bazelbuild/rules_cc.git",
+				Commit:      "b1c40e1de81913a3c40e5948f78719c28152486d",
+				StripPrefix: "rules_cc",
+				Patches:     map[string]string{"module_dot_bazel.patch": "sha256-abc="},
+				PatchStrip:  1,
+			},
+			want: `git_repository(
+    name = "rules_cc",
+    remote = "https://github.com/bazelbuild/rules_cc.git",
+    commit = "b1c40e1de81913a3c40e5948f78719c28152486d",
+    strip_prefix = "rules_cc",
+    patches = ["module_dot_bazel.patch"],
+    patch_args = ["-p1"],
+)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.src.RenderGitRepository("rules_cc")
+			if err != nil {
+				t.Fatalf("RenderGitRepository() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderGitRepository() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		for name, src := range map[string]*Source{
+			"archive":    {URL: "https://example.com/a.zip"},
+			"local_path": {Type: "local_path", Path: "../mod"},
+			"no commit":  {Type: "git_repository", Remote: "https://github.com/owner/repo.git"},
+			"no remote":  {Type: "git_repository", Commit: "abc123"},
+		} {
+			if _, err := src.RenderGitRepository("repo"); err == nil {
+				t.Errorf("%s: RenderGitRepository() should fail", name)
+			}
+		}
+	})
+}
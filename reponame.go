@@ -0,0 +1,43 @@
+package bcr
+
+// RepoNameScheme selects a convention Bazel has used for canonical
+// repository names of Bzlmod modules.
+type RepoNameScheme int
+
+const (
+	// RepoNamePlus is the scheme used by Bazel 8 and later, where the
+	// module name and version are separated by "+" (e.g., "rules_go+").
+	RepoNamePlus RepoNameScheme = iota
+
+	// RepoNameTilde is the scheme used by Bazel 7, where the module name
+	// and version are separated by "~" (e.g., "rules_go~0.41.0").
+	RepoNameTilde
+)
+
+// separator returns the character between module name and version.
+func (s RepoNameScheme) separator() string {
+	if s == RepoNameTilde {
+		return "~"
+	}
+	return "+"
+}
+
+// CanonicalRepoName returns the canonical repository name Bazel assigns
+// to a module's repository under scheme s.
+//
+// The version is appended only when non-empty. Since Bazel 7.1 it is
+// omitted unless several versions of the module coexist in the graph
+// (via multiple_version_override), so callers should usually pass an
+// empty version; Bazel 7.0 always included it.
+func (s RepoNameScheme) CanonicalRepoName(module, version string) string {
+	return module + s.separator() + version
+}
+
+// CanonicalRepoName returns the canonical repository name for a module
+// using the current Bazel scheme ([RepoNamePlus]).
+//
+// For example, CanonicalRepoName("rules_go", "") returns "rules_go+".
+// Use [RepoNameTilde] for names produced by Bazel 7.
+func CanonicalRepoName(module, version string) string {
+	return RepoNamePlus.CanonicalRepoName(module, version)
+}
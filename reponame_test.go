@@ -0,0 +1,35 @@
+package bcr
+
+import "testing"
+
+func TestCanonicalRepoName(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  RepoNameScheme
+		module  string
+		version string
+		want    string
+	}{
+		// Bazel 8+ uses "+" and omits the version for single-version modules
+		{"plus single version", RepoNamePlus, "rules_go", "", "rules_go+"},
+		{"plus multiple versions", RepoNamePlus, "rules_go", "0.50.1", "rules_go+0.50.1"},
+		// Bazel 7.0 always included the version
+		{"tilde with version", RepoNameTilde, "rules_go", "0.41.0", "rules_go~0.41.0"},
+		// Bazel 7.1+ omits it for single-version modules
+		{"tilde single version", RepoNameTilde, "protobuf", "", "protobuf~"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scheme.CanonicalRepoName(tt.module, tt.version); got != tt.want {
+				t.Errorf("CanonicalRepoName(%q, %q) = %q, want %q", tt.module, tt.version, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("default scheme is plus", func(t *testing.T) {
+		if got := CanonicalRepoName("rules_go", ""); got != "rules_go+" {
+			t.Errorf("CanonicalRepoName() = %q, want %q", got, "rules_go+")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package bcr
+
+import (
+	"cmp"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// ResolvedSet is a set of resolved module versions making up a dependency
+// graph, such as the result of calling [Client.Resolve] for each module a
+// build uses.
+type ResolvedSet []*ResolvedModule
+
+// cycloneDXHashAlgs maps SRI algorithm names to CycloneDX hash algorithms.
+var cycloneDXHashAlgs = map[string]string{
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// ToCycloneDX returns a CycloneDX 1.5 SBOM in JSON listing the modules of
+// the set, for supply-chain tooling.
+//
+// Each module is a library component identified by its purl
+// ("pkg:bazel/<name>@<version>"), with the integrity hashes of its source
+// archive as hex-encoded hashes. A module listed more than once with the
+// same version is exported once, from its first entry. Components are
+// sorted by name and version, so the same set always gives the same
+// document.
+//
+// The dependencies section links each module to the version of each
+// dependency that it declares in Deps. If the set lacks that version but
+// holds exactly one version of the dependency, as after version
+// resolution has upgraded it, the link is to that version instead.
+// Dependencies not in the set, or with several other versions in it, are
+// left out.
+//
+// An error is returned if a module has no name or version, or if its
+// source has a malformed integrity.
+func (s ResolvedSet) ToCycloneDX() ([]byte, error) {
+	type hash struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	}
+	type component struct {
+		Type    string `json:"type"`
+		BOMRef  string `json:"bom-ref"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		PURL    string `json:"purl"`
+		Hashes  []hash `json:"hashes,omitempty"`
+	}
+	type dependency struct {
+		Ref       string   `json:"ref"`
+		DependsOn []string `json:"dependsOn,omitempty"`
+	}
+
+	modules := make([]*ResolvedModule, 0, len(s))
+	versions := make(map[string][]string) // by module name
+	seen := make(map[string]bool)         // by purl
+	for _, m := range s {
+		if m == nil || m.Name == "" || m.Version == "" {
+			return nil, errors.New("bcr: cannot export SBOM: module without a name or version")
+		}
+		if ref := modulePURL(m.Name, m.Version); !seen[ref] {
+			seen[ref] = true
+			modules = append(modules, m)
+			versions[m.Name] = append(versions[m.Name], m.Version)
+		}
+	}
+	slices.SortFunc(modules, func(a, b *ResolvedModule) int {
+		return cmp.Or(
+			strings.Compare(a.Name, b.Name),
+			CompareVersions(a.Version, b.Version),
+			// Equivalent versions (e.g., differing in build metadata).
+			strings.Compare(a.Version, b.Version),
+		)
+	})
+
+	components := make([]component, 0, len(modules))
+	dependencies := make([]dependency, 0, len(modules))
+	for _, m := range modules {
+		ref := modulePURL(m.Name, m.Version)
+		c := component{Type: "library", BOMRef: ref, Name: m.Name, Version: m.Version, PURL: ref}
+		if m.Source != nil {
+			for _, integrity := range sourceIntegrities(m.Source) {
+				algo, digest, err := ParseIntegrity(integrity)
+				if err != nil {
+					return nil, fmt.Errorf("bcr: cannot export SBOM for %s@%s: %w", m.Name, m.Version, err)
+				}
+				if alg, ok := cycloneDXHashAlgs[algo]; ok {
+					c.Hashes = append(c.Hashes, hash{Alg: alg, Content: hex.EncodeToString(digest)})
+				}
+			}
+		}
+		components = append(components, c)
+
+		var dependsOn []string
+		for _, name := range slices.Sorted(maps.Keys(m.Deps)) {
+			switch candidates := versions[name]; {
+			case slices.Contains(candidates, m.Deps[name]):
+				dependsOn = append(dependsOn, modulePURL(name, m.Deps[name]))
+			case len(candidates) == 1:
+				dependsOn = append(dependsOn, modulePURL(name, candidates[0]))
+			}
+		}
+		dependencies = append(dependencies, dependency{Ref: ref, DependsOn: dependsOn})
+	}
+
+	return json.MarshalIndent(struct {
+		BOMFormat    string       `json:"bomFormat"`
+		SpecVersion  string       `json:"specVersion"`
+		Version      int          `json:"version"`
+		Components   []component  `json:"components"`
+		Dependencies []dependency `json:"dependencies"`
+	}{"CycloneDX", "1.5", 1, components, dependencies}, "", "  ")
+}
+
+// modulePURL returns the package URL of a module version. Characters such
+// as "+" in build metadata are percent-encoded, as the purl spec requires.
+func modulePURL(name, version string) string {
+	return "pkg:bazel/" + name + "@" + strings.ReplaceAll(url.PathEscape(version), "+", "%2B")
+}
@@ -0,0 +1,205 @@
+package bcr
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToCycloneDX(t *testing.T) {
+	set := ResolvedSet{
+		{
+			Name:    "zlib",
+			Version: "1.3.1.bcr.1",
+			Source:  &Source{URL: "https://example.com/zlib.tar.gz", Integrity: ComputeIntegrity([]byte("hello"))},
+			Deps:    map[string]string{},
+		},
+		{
+			Name:    "rules_go",
+			Version: "0.50.1+build.1",
+			Source: &Source{
+				URL:         "https://example.com/rules_go.zip",
+				Integrity:   "sha512-BBBB",
+				Integrities: []string{"sha512-BBBB", "blake3-AAAA"},
+			},
+			// zlib links to the only version in the set; bazel_skylib
+			// is not in the set, so it has no dependency edge.
+			Deps: map[string]string{"zlib": "1.3.1", "bazel_skylib": "1.7.1"},
+		},
+		{Name: "platforms", Version: "0.0.10"},
+	}
+
+	got, err := set.ToCycloneDX()
+	if err != nil {
+		t.Fatalf("ToCycloneDX() error = %v", err)
+	}
+	const want = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "version": 1,
+  "components": [
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/platforms@0.0.10",
+      "name": "platforms",
+      "version": "0.0.10",
+      "purl": "pkg:bazel/platforms@0.0.10"
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/rules_go@0.50.1%2Bbuild.1",
+      "name": "rules_go",
+      "version": "0.50.1+build.1",
+      "purl": "pkg:bazel/rules_go@0.50.1%2Bbuild.1",
+      "hashes": [
+        {
+          "alg": "SHA-512",
+          "content": "041041"
+        }
+      ]
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/zlib@1.3.1.bcr.1",
+      "name": "zlib",
+      "version": "1.3.1.bcr.1",
+      "purl": "pkg:bazel/zlib@1.3.1.bcr.1",
+      "hashes": [
+        {
+          "alg": "SHA-256",
+          "content": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+        }
+      ]
+    }
+  ],
+  "dependencies": [
+    {
+      "ref": "pkg:bazel/platforms@0.0.10"
+    },
+    {
+      "ref": "pkg:bazel/rules_go@0.50.1%2Bbuild.1",
+      "dependsOn": [
+        "pkg:bazel/zlib@1.3.1.bcr.1"
+      ]
+    },
+    {
+      "ref": "pkg:bazel/zlib@1.3.1.bcr.1"
+    }
+  ]
+}`
+	if string(got) != want {
+		t.Errorf("ToCycloneDX() =\n%s\nwant\n%s", got, want)
+	}
+	if !json.Valid(got) {
+		t.Error("ToCycloneDX() returned invalid JSON")
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		reversed := ResolvedSet{set[2], set[1], set[0]}
+		again, err := reversed.ToCycloneDX()
+		if err != nil || string(again) != string(got) {
+			t.Errorf("ToCycloneDX() of reordered set differs: %v", err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := map[string]ResolvedSet{
+			"no version":          {{Name: "zlib"}},
+			"nil module":          {nil},
+			"malformed integrity": {{Name: "zlib", Version: "1.3.1", Source: &Source{Integrity: "sha256"}}},
+		}
+		for name, set := range tests {
+			if _, err := set.ToCycloneDX(); err == nil || !strings.HasPrefix(err.Error(), "bcr: cannot export SBOM") {
+				t.Errorf("%s: error = %v, want SBOM export error", name, err)
+			}
+		}
+	})
+}
+
+func TestToCycloneDXMultipleVersions(t *testing.T) {
+	zlibOld := &ResolvedModule{Name: "zlib", Version: "1.2.13", Deps: map[string]string{}}
+	set := ResolvedSet{
+		{Name: "rules_go", Version: "0.50.1", Deps: map[string]string{"zlib": "1.3.1"}},
+		{Name: "protobuf", Version: "29.0", Deps: map[string]string{"zlib": "1.2.13"}},
+		// Neither version of zlib is the declared 1.2.11, so there is
+		// no edge.
+		{Name: "grpc", Version: "1.66.0", Deps: map[string]string{"zlib": "1.2.11"}},
+		zlibOld,
+		{Name: "zlib", Version: "1.3.1", Deps: map[string]string{}},
+		// A repeated module is exported once.
+		zlibOld,
+	}
+
+	got, err := set.ToCycloneDX()
+	if err != nil {
+		t.Fatalf("ToCycloneDX() error = %v", err)
+	}
+	const want = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "version": 1,
+  "components": [
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/grpc@1.66.0",
+      "name": "grpc",
+      "version": "1.66.0",
+      "purl": "pkg:bazel/grpc@1.66.0"
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/protobuf@29.0",
+      "name": "protobuf",
+      "version": "29.0",
+      "purl": "pkg:bazel/protobuf@29.0"
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/rules_go@0.50.1",
+      "name": "rules_go",
+      "version": "0.50.1",
+      "purl": "pkg:bazel/rules_go@0.50.1"
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/zlib@1.2.13",
+      "name": "zlib",
+      "version": "1.2.13",
+      "purl": "pkg:bazel/zlib@1.2.13"
+    },
+    {
+      "type": "library",
+      "bom-ref": "pkg:bazel/zlib@1.3.1",
+      "name": "zlib",
+      "version": "1.3.1",
+      "purl": "pkg:bazel/zlib@1.3.1"
+    }
+  ],
+  "dependencies": [
+    {
+      "ref": "pkg:bazel/grpc@1.66.0"
+    },
+    {
+      "ref": "pkg:bazel/protobuf@29.0",
+      "dependsOn": [
+        "pkg:bazel/zlib@1.2.13"
+      ]
+    },
+    {
+      "ref": "pkg:bazel/rules_go@0.50.1",
+      "dependsOn": [
+        "pkg:bazel/zlib@1.3.1"
+      ]
+    },
+    {
+      "ref": "pkg:bazel/zlib@1.2.13"
+    },
+    {
+      "ref": "pkg:bazel/zlib@1.3.1"
+    }
+  ]
+}`
+	if string(got) != want {
+		t.Errorf("ToCycloneDX() =\n%s\nwant\n%s", got, want)
+	}
+}
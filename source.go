@@ -0,0 +1,196 @@
+package bcr
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// archiveExtensions are the archive suffixes GitHub serves tag archives with.
+var archiveExtensions = []string{".tar.gz", ".zip"}
+
+// GitHubTag returns the GitHub release tag embedded in the archive URL
+// and reports whether one was found.
+//
+// Recognized URL forms are:
+//   - https://github.com/<owner>/<repo>/releases/download/<tag>/<file>
+//   - https://github.com/<owner>/<repo>/archive/refs/tags/<tag>.tar.gz (or .zip)
+//
+// Other URLs, including archives of plain commits, return false.
+func (s *Source) GitHubTag() (string, bool) {
+	if s == nil || s.URL == "" {
+		return "", false
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || !strings.EqualFold(u.Hostname(), "github.com") {
+		return "", false
+	}
+
+	// <owner>/<repo>/<kind>/...
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+	if len(parts) < 4 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	kind, rest := parts[2], parts[3]
+
+	switch kind {
+	case "releases":
+		tag, file, ok := strings.Cut(strings.TrimPrefix(rest, "download/"), "/")
+		if !strings.HasPrefix(rest, "download/") || !ok || tag == "" || file == "" {
+			return "", false
+		}
+		return tag, true
+	case "archive":
+		ref, ok := strings.CutPrefix(rest, "refs/tags/")
+		if !ok {
+			return "", false
+		}
+		for _, ext := range archiveExtensions {
+			if tag, ok := strings.CutSuffix(ref, ext); ok && tag != "" {
+				return tag, true
+			}
+		}
+	}
+	return "", false
+}
+
+// AllURLs returns the archive download locations in the order they should
+// be tried: URLs if present, otherwise URL alone. It returns nil if the
+// source has neither.
+func (s *Source) AllURLs() []string {
+	switch {
+	case s == nil:
+		return nil
+	case len(s.URLs) > 0:
+		return slices.Clone(s.URLs)
+	case s.URL != "":
+		return []string{s.URL}
+	}
+	return nil
+}
+
+// Filename returns a local file name for the source archive: the last
+// path segment of the first of [Source.AllURLs] (e.g.,
+// "rules_go-v0.50.1.zip"), ignoring any query string or trailing slash.
+// If the URL has no usable segment, the name is "archive" with
+// ArchiveType as its extension.
+//
+// Filename returns empty string for sources that are not archives, or if
+// no name can be derived.
+func (s *Source) Filename() string {
+	if s.SourceType() != "archive" {
+		return ""
+	}
+	if urls := s.AllURLs(); len(urls) > 0 {
+		if u, err := url.Parse(urls[0]); err == nil {
+			name := path.Base(strings.TrimRight(u.Path, "/"))
+			if isPathSegment(name) {
+				return name
+			}
+		}
+	}
+	if ext := strings.TrimPrefix(s.ArchiveType, "."); isPathSegment(ext) {
+		return "archive." + ext
+	}
+	return ""
+}
+
+// Validate checks that the source has the fields its type needs: archive
+// sources need a download URL and a well-formed integrity, git_repository
+// sources a Remote and Commit, and local_path sources a Path. Patch
+// integrities must be well-formed too, and the type must be one of those
+// three. Every problem found is reported, joined into one error; nil
+// means the source is usable.
+func (s *Source) Validate() error {
+	if s == nil {
+		return errors.New("bcr: invalid source: no source")
+	}
+	var errs []error
+	missing := func(field string) {
+		errs = append(errs, fmt.Errorf("bcr: invalid %s source: missing %s", s.SourceType(), field))
+	}
+
+	switch s.SourceType() {
+	case "archive":
+		if len(s.AllURLs()) == 0 {
+			missing("url")
+		} else if slices.Contains(s.URLs, "") {
+			errs = append(errs, errors.New("bcr: invalid archive source: empty entry in urls"))
+		}
+		if len(sourceIntegrities(s)) == 0 {
+			missing("integrity")
+		}
+	case "git_repository":
+		if s.Remote == "" {
+			missing("remote")
+		}
+		if s.Commit == "" {
+			missing("commit")
+		}
+	case "local_path":
+		if s.Path == "" {
+			missing("path")
+		}
+	default:
+		errs = append(errs, fmt.Errorf("bcr: invalid source: unknown type %q", s.Type))
+	}
+	if err := validateSourceIntegrity(s); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// HostAllowed reports whether every location the source is fetched from
+// has a host in allowed.
+//
+// Every archive URL (see [Source.AllURLs]) and the git Remote are checked;
+// hosts are compared case-insensitively and must match exactly
+// (subdomains are not implied). A source with no remote location, such as
+// a local_path source, is allowed. Unparseable locations are not.
+func (s *Source) HostAllowed(allowed []string) bool {
+	_, rejected := s.rejectedHost(allowed)
+	return !rejected
+}
+
+// rejectedHost returns the first host of s not in allowed, as checked by
+// [Source.HostAllowed]. For an unparseable location, the location itself
+// is returned.
+func (s *Source) rejectedHost(allowed []string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	for _, loc := range append(s.AllURLs(), s.Remote) {
+		if loc == "" {
+			continue
+		}
+		host, ok := locationHost(loc)
+		if !ok {
+			return loc, true
+		}
+		if !slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(a, host) }) {
+			return host, true
+		}
+	}
+	return "", false
+}
+
+// locationHost returns the host of a URL or of an scp-style git remote
+// such as "git@github.com:owner/repo.git".
+func locationHost(loc string) (string, bool) {
+	if !strings.Contains(loc, "://") {
+		if _, rest, ok := strings.Cut(loc, "@"); ok {
+			if host, _, ok := strings.Cut(rest, ":"); ok && host != "" {
+				return host, true
+			}
+		}
+		return "", false
+	}
+	u, err := url.Parse(loc)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	return u.Hostname(), true
+}
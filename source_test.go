@@ -0,0 +1,242 @@
+package bcr
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSourceGitHubTag(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantTag string
+		wantOK  bool
+	}{
+		{"https://github.com/bazel-contrib/rules_go/releases/download/v0.50.1/rules_go-v0.50.1.zip", "v0.50.1", true},
+		{"https://github.com/protocolbuffers/protobuf/releases/download/v29.0/protobuf-29.0.tar.gz", "v29.0", true},
+		{"https://github.com/bazelbuild/bazel-skylib/archive/refs/tags/1.7.1.tar.gz", "1.7.1", true},
+		{"https://github.com/abseil/abseil-cpp/archive/refs/tags/20240722.0.zip", "20240722.0", true},
+		{"https://github.com/owner/repo/archive/refs/tags/release/1.0.tar.gz", "release/1.0", true},
+
+		// Not recognizable tag URLs
+		{"https://github.com/owner/repo/archive/0123456789abcdef.tar.gz", "", false},
+		{"https://github.com/owner/repo/releases/download/v1.0", "", false},
+		{"https://github.com/owner/repo/releases/latest", "", false},
+		{"https://example.com/owner/repo/releases/download/v1.0/file.zip", "", false},
+		{"https://github.com/owner", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			s := &Source{URL: tt.url}
+			tag, ok := s.GitHubTag()
+			if tag != tt.wantTag || ok != tt.wantOK {
+				t.Errorf("GitHubTag() = (%q, %v), want (%q, %v)", tag, ok, tt.wantTag, tt.wantOK)
+			}
+		})
+	}
+
+	t.Run("nil safety", func(t *testing.T) {
+		var s *Source
+		if _, ok := s.GitHubTag(); ok {
+			t.Error("nil.GitHubTag() ok = true, want false")
+		}
+	})
+}
+
+func TestSourceHostAllowed(t *testing.T) {
+	allowed := []string{"github.com", "mirror.example.com"}
+
+	tests := []struct {
+		name string
+		src  *Source
+		want bool
+	}{
+		{"allowed archive", &Source{URL: "https://github.com/owner/repo/archive/v1.tar.gz"}, true},
+		{"case-insensitive", &Source{URL: "https://GitHub.com/owner/repo/archive/v1.tar.gz"}, true},
+		{"with port", &Source{URL: "https://mirror.example.com:8443/repo.zip"}, true},
+		{"disallowed archive", &Source{URL: "https://evil.example.com/repo.zip"}, false},
+		{"subdomain not implied", &Source{URL: "https://codeload.github.com/owner/repo"}, false},
+		{"allowed git remote", &Source{Type: "git_repository", Remote: "https://github.com/owner/repo.git"}, true},
+		{"scp-style remote", &Source{Type: "git_repository", Remote: "git@github.com:owner/repo.git"}, true},
+		{"disallowed git remote", &Source{Type: "git_repository", Remote: "https://gitlab.com/owner/repo.git"}, false},
+		{"local path", &Source{Type: "local_path", Path: "/src"}, true},
+		{"unparseable", &Source{URL: "not a url"}, false},
+		{"nil", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.src.HostAllowed(allowed); got != tt.want {
+				t.Errorf("HostAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceIntegrityDecode(t *testing.T) {
+	tests := []struct {
+		name            string
+		json            string
+		wantIntegrity   string
+		wantIntegrities []string
+	}{
+		{"scalar", `{"url": "u", "integrity": "sha256-AAAA"}`, "sha256-AAAA", []string{"sha256-AAAA"}},
+		{"array", `{"url": "u", "integrity": ["sha512-BBBB", "sha256-AAAA"]}`, "sha512-BBBB", []string{"sha512-BBBB", "sha256-AAAA"}},
+		{"empty array", `{"url": "u", "integrity": []}`, "", nil},
+		{"absent", `{"url": "u"}`, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src Source
+			if err := json.Unmarshal([]byte(tt.json), &src); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if src.URL != "u" {
+				t.Errorf("URL = %q, want %q", src.URL, "u")
+			}
+			if src.Integrity != tt.wantIntegrity {
+				t.Errorf("Integrity = %q, want %q", src.Integrity, tt.wantIntegrity)
+			}
+			if !slices.Equal(src.Integrities, tt.wantIntegrities) {
+				t.Errorf("Integrities = %v, want %v", src.Integrities, tt.wantIntegrities)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		var src Source
+		if err := json.Unmarshal([]byte(`{"integrity": 42}`), &src); err == nil {
+			t.Error("expected error for numeric integrity")
+		}
+	})
+
+	t.Run("validation covers every hash", func(t *testing.T) {
+		var src Source
+		data := `{"integrity": ["` + sha256Integrity([]byte("x")) + `", "sha256-bad"]}`
+		if err := json.Unmarshal([]byte(data), &src); err != nil {
+			t.Fatal(err)
+		}
+		if err := validateSourceIntegrity(&src); err == nil {
+			t.Error("expected error for malformed second integrity")
+		}
+	})
+}
+
+func TestSourceFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		src  *Source
+		want string
+	}{
+		{"github release", &Source{URL: "https://github.com/bazel-contrib/rules_go/releases/download/v0.50.1/rules_go-v0.50.1.zip"}, "rules_go-v0.50.1.zip"},
+		{"github tag archive", &Source{URL: "https://github.com/protocolbuffers/protobuf/archive/refs/tags/v29.0.tar.gz"}, "v29.0.tar.gz"},
+		{"raw archive", &Source{URL: "https://mirror.example.com/archives/zlib-1.3.1.tar.xz"}, "zlib-1.3.1.tar.xz"},
+		{"query string", &Source{URL: "https://example.com/dl/abseil-20240722.0.tar.gz?raw=true#frag"}, "abseil-20240722.0.tar.gz"},
+		{"trailing slash", &Source{URL: "https://example.com/dl/abseil.tar.gz/"}, "abseil.tar.gz"},
+		{"escaped", &Source{URL: "https://example.com/dl/my%20archive.zip"}, "my archive.zip"},
+		{"archive type fallback", &Source{URL: "https://example.com/?id=42", ArchiveType: "tar.gz"}, "archive.tar.gz"},
+		{"host only", &Source{URL: "https://example.com/", ArchiveType: "zip"}, "archive.zip"},
+		{"no name", &Source{URL: "https://example.com"}, ""},
+		{"git", &Source{Type: "git_repository", Remote: "https://github.com/owner/repo.git"}, ""},
+		{"local path", &Source{Type: "local_path", Path: "../mod"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.src.Filename(); got != tt.want {
+				t.Errorf("Filename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceAllURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{"single url", `{"url": "https://example.com/a.tar.gz"}`, []string{"https://example.com/a.tar.gz"}},
+		{"mirrors", `{"url": "https://example.com/a.tar.gz", "urls": ["https://mirror.example.com/a.tar.gz", "https://example.com/a.tar.gz"]}`, []string{"https://mirror.example.com/a.tar.gz", "https://example.com/a.tar.gz"}},
+		{"urls only", `{"urls": ["https://mirror.example.com/a.tar.gz"]}`, []string{"https://mirror.example.com/a.tar.gz"}},
+		{"none", `{"type": "git_repository", "remote": "https://github.com/owner/repo.git"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src Source
+			if err := json.Unmarshal([]byte(tt.json), &src); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got := src.AllURLs(); !slices.Equal(got, tt.want) {
+				t.Errorf("AllURLs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("mirror hosts checked", func(t *testing.T) {
+		src := &Source{URLs: []string{"https://github.com/a.tar.gz", "https://evil.example.com/a.tar.gz"}}
+		if src.HostAllowed([]string{"github.com"}) {
+			t.Error("HostAllowed() = true, want false for a disallowed mirror")
+		}
+	})
+
+	t.Run("filename from mirror", func(t *testing.T) {
+		src := &Source{URLs: []string{"https://mirror.example.com/dl/zlib-1.3.1.tar.gz"}}
+		if got := src.Filename(); got != "zlib-1.3.1.tar.gz" {
+			t.Errorf("Filename() = %q, want %q", got, "zlib-1.3.1.tar.gz")
+		}
+	})
+
+	t.Run("nil safety", func(t *testing.T) {
+		var src *Source
+		if got := src.AllURLs(); got != nil {
+			t.Errorf("nil.AllURLs() = %q, want nil", got)
+		}
+	})
+}
+
+func TestSourceValidate(t *testing.T) {
+	integrity := sha256Integrity([]byte("archive"))
+	tests := []struct {
+		name    string
+		src     *Source
+		wantErr []string // substrings of the error; nil means valid
+	}{
+		{"archive", &Source{URL: "https://example.com/a.tar.gz", Integrity: integrity}, nil},
+		{"archive with mirrors", &Source{Type: "archive", URLs: []string{"https://a.example/x.zip", "https://b.example/x.zip"}, Integrity: integrity}, nil},
+		{"archive missing url and integrity", &Source{}, []string{"missing url", "missing integrity"}},
+		{"archive with empty mirror", &Source{URLs: []string{"https://a.example/x.zip", ""}, Integrity: integrity}, []string{"empty entry in urls"}},
+		{"archive with malformed integrity", &Source{URL: "https://example.com/a.tar.gz", Integrity: "sha256-bad"}, []string{"malformed integrity"}},
+		{"archive with malformed patch", &Source{URL: "https://example.com/a.tar.gz", Integrity: integrity, Patches: map[string]string{"fix.patch": "nope"}}, []string{"patch fix.patch"}},
+		{"git", &Source{Type: "git_repository", Remote: "https://github.com/o/r.git", Commit: "abc123"}, nil},
+		{"git missing remote and commit", &Source{Type: "git_repository"}, []string{"missing remote", "missing commit"}},
+		{"local path", &Source{Type: "local_path", Path: "../mod"}, nil},
+		{"local path missing path", &Source{Type: "local_path"}, []string{"missing path"}},
+		{"unknown type", &Source{Type: "svn", URL: "https://example.com"}, []string{`unknown type "svn"`}},
+		{"nil", nil, []string{"no source"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.src.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want errors containing %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
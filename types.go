@@ -1,6 +1,13 @@
 package bcr
 
-import "strings"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
 
 // Metadata contains information about a module in the registry.
 //
@@ -22,6 +29,63 @@ type Metadata struct {
 
 	// Repository lists source repository identifiers (e.g., "github:owner/repo").
 	Repository []string `json:"repository,omitempty"`
+
+	// yankDetails holds yank entries decoded from the extended object
+	// form of yanked_versions. Entries in the plain string form only
+	// appear in YankedVersions.
+	yankDetails map[string]YankInfo
+}
+
+// YankInfo describes a yanked version.
+type YankInfo struct {
+	// Reason explains why the version was yanked.
+	Reason string `json:"reason,omitempty"`
+
+	// Date is when the version was yanked, as recorded by the registry.
+	// Empty if the registry does not record it.
+	Date string `json:"date,omitempty"`
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+//
+// Each yanked_versions entry may be either a reason string (the current
+// BCR schema) or an object with "reason" and "date" fields. In both cases
+// YankedVersions maps the version to its reason; use
+// [Metadata.YankReasonDetailed] to access the date.
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	type metadataAlias Metadata
+	aux := struct {
+		*metadataAlias
+		YankedVersions map[string]json.RawMessage `json:"yanked_versions,omitempty"`
+	}{metadataAlias: (*metadataAlias)(m)}
+
+	m.YankedVersions = nil
+	m.yankDetails = nil
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.YankedVersions == nil {
+		return nil
+	}
+
+	m.YankedVersions = make(map[string]string, len(aux.YankedVersions))
+	for version, raw := range aux.YankedVersions {
+		var reason string
+		if err := json.Unmarshal(raw, &reason); err == nil {
+			m.YankedVersions[version] = reason
+			continue
+		}
+		var info YankInfo
+		if err := json.Unmarshal(raw, &info); err != nil {
+			return fmt.Errorf("yanked_versions[%q]: must be a string or object: %w", version, err)
+		}
+		m.YankedVersions[version] = info.Reason
+		if m.yankDetails == nil {
+			m.yankDetails = make(map[string]YankInfo)
+		}
+		m.yankDetails[version] = info
+	}
+	return nil
 }
 
 // IsYanked reports whether the given version is yanked.
@@ -41,14 +105,203 @@ func (m *Metadata) YankReason(version string) string {
 	return m.YankedVersions[version]
 }
 
+// YankReasonDetailed returns the yank details for a version and reports
+// whether the version is yanked.
+//
+// Date is only populated when the registry uses the extended object form
+// of yanked_versions.
+func (m *Metadata) YankReasonDetailed(version string) (YankInfo, bool) {
+	if m == nil || m.YankedVersions == nil {
+		return YankInfo{}, false
+	}
+	if info, ok := m.yankDetails[version]; ok {
+		return info, true
+	}
+	reason, ok := m.YankedVersions[version]
+	if !ok {
+		return YankInfo{}, false
+	}
+	return YankInfo{Reason: reason}, true
+}
+
+// YankedVersion is a yanked version and the reason it was yanked. See
+// [Metadata.YankedList].
+type YankedVersion struct {
+	Version string
+	Reason  string
+}
+
+// YankedList returns the yanked versions sorted by [CompareVersions],
+// for deterministic iteration. It returns an empty slice if no versions
+// are yanked.
+func (m *Metadata) YankedList() []YankedVersion {
+	if m == nil {
+		return []YankedVersion{}
+	}
+	list := make([]YankedVersion, 0, len(m.YankedVersions))
+	for v, reason := range m.YankedVersions {
+		list = append(list, YankedVersion{Version: v, Reason: reason})
+	}
+	slices.SortFunc(list, func(a, b YankedVersion) int {
+		if c := CompareVersions(a.Version, b.Version); c != 0 {
+			return c
+		}
+		// Equivalent versions (e.g., differing in build metadata).
+		return strings.Compare(a.Version, b.Version)
+	})
+	return list
+}
+
 // Latest returns the latest non-yanked version, or empty string if none available.
+//
+// The latest version is the last in registry order; use
+// [Metadata.SortedVersions] if the registry may list versions out of order.
 func (m *Metadata) Latest() string {
+	return m.latest(false)
+}
+
+// LatestStable returns the latest non-yanked, non-prerelease version.
+// Falls back to the latest non-yanked prerelease if no stable version exists.
+// Returns empty string if all versions are yanked.
+//
+// Like [Metadata.Latest], this uses registry order.
+func (m *Metadata) LatestStable() string {
+	return m.latestStable(false)
+}
+
+// Newest returns the highest version listed, by [CompareVersions], or
+// empty string if there are none. Unlike [Metadata.Latest] and
+// [Metadata.LatestStable], it does not skip yanked versions or
+// prereleases, and it does not depend on registry order; it is the last
+// of [Metadata.SortedVersions]. Use it for "newest published" views, not
+// to pick a version to depend on.
+//
+// As in SortedVersions, strings that are not valid versions are newer
+// than any valid one.
+func (m *Metadata) Newest() string {
+	if m == nil || len(m.Versions) == 0 {
+		return ""
+	}
+	return slices.MaxFunc(m.Versions, CompareVersions)
+}
+
+// SortedVersions returns the versions in ascending version order,
+// regardless of registry order.
+//
+// Versions are compared by their dot-separated release identifiers,
+// numerically where numeric (so 1.2.0 < 1.10.0), and a prerelease sorts
+// before its release. Strings that are not valid versions sort after all
+// valid ones, in lexical order.
+func (m *Metadata) SortedVersions() []string {
+	if m == nil {
+		return nil
+	}
+	versions := slices.Clone(m.Versions)
+	slices.SortStableFunc(versions, CompareVersions)
+	return versions
+}
+
+// VersionsSince returns the non-yanked versions strictly newer than
+// baseline, in version order. baseline need not be listed itself.
+func (m *Metadata) VersionsSince(baseline string) []string {
+	var newer []string
+	for _, v := range m.SortedVersions() {
+		if CompareVersions(v, baseline) > 0 && !m.IsYanked(v) {
+			newer = append(newer, v)
+		}
+	}
+	return newer
+}
+
+// NextVersion returns the first non-yanked version after v in version
+// order (see [Metadata.SortedVersions]). It reports false if v is not
+// listed or no later non-yanked version exists.
+func (m *Metadata) NextVersion(v string) (string, bool) {
+	return m.adjacentVersion(v, 1)
+}
+
+// PreviousVersion returns the last non-yanked version before v in
+// version order. It reports false if v is not listed or no earlier
+// non-yanked version exists.
+func (m *Metadata) PreviousVersion(v string) (string, bool) {
+	return m.adjacentVersion(v, -1)
+}
+
+// adjacentVersion walks the sorted versions from v in direction step
+// (1 or -1) to the nearest non-yanked version.
+func (m *Metadata) adjacentVersion(v string, step int) (string, bool) {
+	sorted := m.SortedVersions()
+	i := slices.Index(sorted, v)
+	if i < 0 {
+		return "", false
+	}
+	for i += step; i >= 0 && i < len(sorted); i += step {
+		if !m.IsYanked(sorted[i]) {
+			return sorted[i], true
+		}
+	}
+	return "", false
+}
+
+// LatestMatching returns the newest non-yanked version, in version
+// order, that satisfies constraint.
+//
+// A constraint is a list of terms separated by spaces or commas, all of
+// which must hold:
+//
+//	1.2.3, =1.2.3, ==1.2.3  exactly 1.2.3
+//	!=1.2.3                 any version but 1.2.3
+//	>1.2.3, >=1.2.3         newer than (or equal to) 1.2.3
+//	<1.2.3, <=1.2.3         older than (or equal to) 1.2.3
+//	~1.4.2                  >=1.4.2 and below 1.5 (~1.4 likewise; ~1 is below 2)
+//	^1.2.3                  >=1.2.3 and below 2 (^0.2.3 is below 0.3, ^0.0.3 below 0.0.4)
+//	*                       any version
+//
+// For example, ">=1.0.0 <2.0.0" or "~1.4". Versions are ordered as by
+// [CompareVersions]. Upper bounds exclude prereleases of the bound, so
+// "<2.0.0" rejects 2.0.0-rc1; prereleases are otherwise treated like
+// any other version.
+//
+// Returns an error describing the problem if the constraint is malformed,
+// or one matching [ErrNotFound] if no version satisfies it.
+func (m *Metadata) LatestMatching(constraint string) (string, error) {
+	bounds, err := parseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	versions := m.SortedVersions()
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if m.IsYanked(v) {
+			continue
+		}
+		if slices.ContainsFunc(bounds, func(b versionBound) bool { return !b.matches(v) }) {
+			continue
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: no version matches %q", ErrNotFound, constraint)
+}
+
+// orderedVersions returns the versions in registry order, or in version
+// order if sorted is set.
+func (m *Metadata) orderedVersions(sorted bool) []string {
+	if sorted {
+		return m.SortedVersions()
+	}
+	return m.Versions
+}
+
+// latest implements [Metadata.Latest], optionally using version order.
+func (m *Metadata) latest(sorted bool) string {
 	if m == nil || len(m.Versions) == 0 {
 		return ""
 	}
+	versions := m.orderedVersions(sorted)
 	// Iterate from end (newest) to find first non-yanked
-	for i := len(m.Versions) - 1; i >= 0; i-- {
-		v := m.Versions[i]
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
 		if !m.IsYanked(v) {
 			return v
 		}
@@ -56,17 +309,17 @@ func (m *Metadata) Latest() string {
 	return ""
 }
 
-// LatestStable returns the latest non-yanked, non-prerelease version.
-// Falls back to the latest non-yanked prerelease if no stable version exists.
-// Returns empty string if all versions are yanked.
-func (m *Metadata) LatestStable() string {
+// latestStable implements [Metadata.LatestStable], optionally using
+// version order.
+func (m *Metadata) latestStable(sorted bool) string {
 	if m == nil || len(m.Versions) == 0 {
 		return ""
 	}
+	versions := m.orderedVersions(sorted)
 
 	// First pass: find latest stable (non-prerelease, non-yanked)
-	for i := len(m.Versions) - 1; i >= 0; i-- {
-		v := m.Versions[i]
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
 		if m.IsYanked(v) {
 			continue
 		}
@@ -76,8 +329,8 @@ func (m *Metadata) LatestStable() string {
 	}
 
 	// Second pass: any non-yanked version (including prerelease)
-	for i := len(m.Versions) - 1; i >= 0; i-- {
-		v := m.Versions[i]
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
 		if !m.IsYanked(v) {
 			return v
 		}
@@ -86,6 +339,37 @@ func (m *Metadata) LatestStable() string {
 	return ""
 }
 
+// StableVersions returns the versions that are neither yanked nor
+// prereleases, in ascending version order as by [Metadata.SortedVersions].
+func (m *Metadata) StableVersions() []string {
+	if m == nil {
+		return nil
+	}
+	var versions []string
+	for _, v := range m.SortedVersions() {
+		if !m.IsYanked(v) && !IsPrerelease(v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// PrereleaseVersions returns the versions that are prereleases, as
+// reported by [IsPrerelease], and not yanked, in ascending version order
+// as by [Metadata.SortedVersions].
+func (m *Metadata) PrereleaseVersions() []string {
+	if m == nil {
+		return nil
+	}
+	var versions []string
+	for _, v := range m.SortedVersions() {
+		if !m.IsYanked(v) && IsPrerelease(v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
 // prereleaseIndicators are common version string patterns indicating prereleases.
 var prereleaseIndicators = []string{"-rc", "-alpha", "-beta", "-dev", "-pre"}
 
@@ -113,6 +397,108 @@ func (m *Metadata) HasVersion(version string) bool {
 	return false
 }
 
+// OrderingIssues reports problems with the order of Versions, which
+// should list each version once in ascending order. Each note names a
+// duplicate version or a version listed after a newer one. It returns
+// nil if Versions is correctly ordered.
+func (m *Metadata) OrderingIssues() []string {
+	if m == nil {
+		return nil
+	}
+	var issues []string
+	seen := make(map[string]int, len(m.Versions))
+	for i, v := range m.Versions {
+		if first, ok := seen[v]; ok {
+			issues = append(issues, fmt.Sprintf("version %q is listed more than once (positions %d and %d)", v, first, i))
+			continue
+		}
+		seen[v] = i
+		if i > 0 && CompareVersions(m.Versions[i-1], v) > 0 {
+			issues = append(issues, fmt.Sprintf("version %q at position %d is listed after newer version %q", v, i, m.Versions[i-1]))
+		}
+	}
+	return issues
+}
+
+// Validate checks the metadata for problems a registry linter should
+// reject: an empty Versions list, yanked versions missing from Versions,
+// maintainers with neither a name nor a GitHub username, and Repository
+// entries not of the form host:owner/repo (e.g., "github:bazelbuild/rules_go").
+// Every problem found is reported, joined into one error; nil means the
+// metadata is valid. See [Metadata.OrderingIssues] for checking the order
+// of Versions.
+func (m *Metadata) Validate() error {
+	if m == nil {
+		return errors.New("bcr: invalid metadata: no metadata")
+	}
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("bcr: invalid metadata: "+format, args...))
+	}
+
+	if len(m.Versions) == 0 {
+		invalid("no versions")
+	}
+	for _, v := range slices.Sorted(maps.Keys(m.YankedVersions)) {
+		if !m.HasVersion(v) {
+			invalid("yanked version %q is not in versions", v)
+		}
+	}
+	for i, maint := range m.Maintainers {
+		if maint.Name == "" && maint.GitHub == "" {
+			invalid("maintainer %d has neither a name nor a github username", i)
+		}
+	}
+	for _, repo := range m.Repository {
+		if !isRepositoryID(repo) {
+			invalid("repository %q is not of the form host:owner/repo", repo)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isRepositoryID reports whether s has the form host:owner/repo. Repositories
+// in nested groups, such as "gitlab:group/subgroup/repo", are accepted.
+func isRepositoryID(s string) bool {
+	host, repo, ok := strings.Cut(s, ":")
+	if !ok || host == "" || strings.ContainsAny(host, "/ ") {
+		return false
+	}
+	parts := strings.Split(repo, "/")
+	return len(parts) >= 2 && !slices.ContainsFunc(parts, func(p string) bool {
+		return p == "" || strings.ContainsAny(p, ": ")
+	})
+}
+
+// repositoryHosts maps the host part of a Repository identifier to the
+// base URL of the code host it names.
+var repositoryHosts = map[string]string{
+	"github":    "https://github.com/",
+	"gitlab":    "https://gitlab.com/",
+	"bitbucket": "https://bitbucket.org/",
+	"codeberg":  "https://codeberg.org/",
+}
+
+// RepositoryURLs converts each Repository identifier to a web URL that
+// can also be used for cloning, such as "https://github.com/owner/repo"
+// for "github:owner/repo". Identifiers with an unrecognized host or not
+// of the form host:owner/repo are skipped.
+func (m *Metadata) RepositoryURLs() []string {
+	if m == nil {
+		return nil
+	}
+	var urls []string
+	for _, repo := range m.Repository {
+		host, path, _ := strings.Cut(repo, ":")
+		base, ok := repositoryHosts[host]
+		if !ok || !isRepositoryID(repo) {
+			continue
+		}
+		urls = append(urls, base+path)
+	}
+	return urls
+}
+
 // Source describes how to fetch a module version's source code.
 //
 // This corresponds to the source.json file in a Bazel registry.
@@ -126,10 +512,22 @@ type Source struct {
 	// URL is the download URL for archive sources.
 	URL string `json:"url,omitempty"`
 
+	// URLs lists mirror download URLs for archive sources, tried in
+	// order. Most sources only set URL; use [Source.AllURLs] to get the
+	// locations of either form.
+	URLs []string `json:"urls,omitempty"`
+
 	// Integrity is the Subresource Integrity hash (e.g., "sha256-...").
-	// Used to verify the downloaded archive.
+	// Used to verify the downloaded archive. If source.json lists several
+	// hashes, this is the first one.
 	Integrity string `json:"integrity,omitempty"`
 
+	// Integrities lists every acceptable integrity hash for the archive.
+	// It is decoded from either a scalar or an array "integrity" field;
+	// a match against any entry is sufficient. It is not encoded by
+	// [json.Marshal], which writes Integrity only.
+	Integrities []string `json:"-"`
+
 	// StripPrefix is the directory prefix to strip from archive contents.
 	StripPrefix string `json:"strip_prefix,omitempty"`
 
@@ -162,6 +560,43 @@ type Source struct {
 	Path string `json:"path,omitempty"`
 }
 
+// UnmarshalJSON implements [json.Unmarshaler].
+//
+// The "integrity" field may be a single SRI string or an array of them,
+// populating both Integrity and Integrities.
+func (s *Source) UnmarshalJSON(data []byte) error {
+	type sourceAlias Source
+	aux := struct {
+		*sourceAlias
+		Integrity json.RawMessage `json:"integrity,omitempty"`
+	}{sourceAlias: (*sourceAlias)(s)}
+
+	s.Integrity = ""
+	s.Integrities = nil
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.Integrity) == 0 || string(aux.Integrity) == "null" {
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(aux.Integrity, &single); err == nil {
+		s.Integrity = single
+		s.Integrities = []string{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(aux.Integrity, &multiple); err != nil {
+		return fmt.Errorf("integrity: must be a string or array of strings: %w", err)
+	}
+	if len(multiple) > 0 {
+		s.Integrity = multiple[0]
+		s.Integrities = multiple
+	}
+	return nil
+}
+
 // SourceType returns the effective source type, defaulting to "archive".
 func (s *Source) SourceType() string {
 	if s == nil || s.Type == "" {
@@ -170,6 +605,118 @@ func (s *Source) SourceType() string {
 	return s.Type
 }
 
+// ResolvedModule is a module version together with its source information
+// and direct dependencies, as returned by [Client.Resolve]. Its JSON form
+// is stable, so tools can cache resolution results: encoding the same
+// value always gives the same bytes, and decoding them gives an equal
+// value.
+type ResolvedModule struct {
+	// Name is the module name.
+	Name string `json:"name"`
+
+	// Version is the module version.
+	Version string `json:"version"`
+
+	// Source is the version's source information.
+	Source *Source `json:"source"`
+
+	// Deps maps the names of the modules the version depends on to the
+	// versions it declares, excluding dev dependencies. A version is
+	// empty if the bazel_dep does not declare one.
+	Deps map[string]string `json:"deps"`
+}
+
+// MarshalJSON implements [json.Marshaler]. Deps are encoded sorted by
+// name, and every entry of Source.Integrities is kept.
+func (m ResolvedModule) MarshalJSON() ([]byte, error) {
+	aux := struct {
+		Name    string            `json:"name"`
+		Version string            `json:"version"`
+		Source  json.RawMessage   `json:"source"`
+		Deps    map[string]string `json:"deps"`
+	}{Name: m.Name, Version: m.Version, Source: json.RawMessage("null"), Deps: m.Deps}
+	if m.Source != nil {
+		src, err := marshalSource(m.Source)
+		if err != nil {
+			return nil, err
+		}
+		aux.Source = src
+	}
+	// encoding/json writes map keys in sorted order.
+	return json.Marshal(aux)
+}
+
+// VersionSource pairs a module version with its source information.
+type VersionSource struct {
+	// Version is the module version.
+	Version string
+
+	// Source is the version's source information, or nil if it
+	// could not be fetched.
+	Source *Source
+}
+
+// ModuleRef identifies a specific version of a module.
+type ModuleRef struct {
+	// Name is the module name.
+	Name string
+
+	// Version is the module version.
+	Version string
+}
+
+// String returns the reference in "name@version" form.
+func (r ModuleRef) String() string {
+	return r.Name + "@" + r.Version
+}
+
+// VersionBundle holds the registry files of a single module version.
+// See [Client.VersionBundle].
+type VersionBundle struct {
+	// Module is the module name.
+	Module string
+
+	// Version is the module version.
+	Version string
+
+	// Source is the parsed source.json.
+	Source *Source
+
+	// ModuleFile is the raw MODULE.bazel content.
+	ModuleFile []byte
+
+	// Presubmit is the raw presubmit.yml content, or nil if the version
+	// has none.
+	Presubmit []byte
+
+	// Attestations is the raw attestations.json content, or nil if the
+	// version has none.
+	Attestations []byte
+}
+
+// Attestations is the content of a version's attestations.json, which
+// lists provenance attestations (such as SLSA provenance) for the files
+// that make up the version.
+type Attestations struct {
+	// MediaType identifies the file format and its version.
+	// Example: "application/vnd.build.bazel.registry.attestation+json;version=1.0.0"
+	MediaType string `json:"mediaType"`
+
+	// Attestations maps each attested artifact (e.g., "source.json",
+	// "MODULE.bazel" or the archive file name) to its attestation.
+	Attestations map[string]Attestation `json:"attestations"`
+}
+
+// Attestation locates the attestation of a single artifact.
+type Attestation struct {
+	// URL is where the attestation bundle can be downloaded.
+	URL string `json:"url"`
+
+	// Integrity is the Subresource Integrity hash of the attestation
+	// bundle.
+	Integrity string `json:"integrity"`
+}
+
 // Maintainer represents a module maintainer.
 type Maintainer struct {
 	// Name is the maintainer's display name.
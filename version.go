@@ -0,0 +1,132 @@
+package bcr
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// parsedVersion is a version string split into Bazel's version
+// components: RELEASE[-PRERELEASE][+BUILD]. Build metadata is ignored
+// for ordering.
+type parsedVersion struct {
+	release    []string
+	prerelease []string
+}
+
+// parseVersion parses a version string following Bazel's module version
+// format. The release and prerelease parts are dot-separated identifiers
+// of ASCII letters and digits, and the release must start with a number.
+// It reports false if s is not of that form.
+func parseVersion(s string) (parsedVersion, bool) {
+	s, _, _ = strings.Cut(s, "+")
+	release, prerelease, hasPrerelease := strings.Cut(s, "-")
+
+	var v parsedVersion
+	var ok bool
+	if v.release, ok = splitIdentifiers(release); !ok || !isNumeric(v.release[0]) {
+		return parsedVersion{}, false
+	}
+	if hasPrerelease {
+		if v.prerelease, ok = splitIdentifiers(prerelease); !ok {
+			return parsedVersion{}, false
+		}
+	}
+	return v, true
+}
+
+// splitIdentifiers splits a dot-separated list of non-empty alphanumeric
+// identifiers. Hyphens are also allowed, so that prereleases such as
+// "rc-1" parse.
+func splitIdentifiers(s string) ([]string, bool) {
+	if s == "" {
+		return nil, false
+	}
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, false
+		}
+		for i := 0; i < len(id); i++ {
+			c := id[i]
+			if !isDigit(c) && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c != '-' {
+				return nil, false
+			}
+		}
+	}
+	return ids, true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNumeric(id string) bool {
+	for i := 0; i < len(id); i++ {
+		if !isDigit(id[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareIdentifier orders two version identifiers. Numeric identifiers
+// compare numerically and sort before alphanumeric ones, which compare
+// lexically.
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		// Compare arbitrarily long numbers without overflow.
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if c := cmp.Compare(len(a), len(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// CompareVersions compares two module version strings following Bazel's
+// version ordering. It returns -1 if a < b, 0 if they are equivalent and
+// +1 if a > b.
+//
+// Versions have the form RELEASE[-PRERELEASE][+BUILD]. Release and
+// prerelease identifiers are compared in turn: numeric identifiers
+// numerically (1.2.0 < 1.10.0), before alphanumeric ones, which compare
+// lexically. A prerelease, such as any version [IsPrerelease] reports,
+// sorts before its release (1.0.0-rc1 < 1.0.0). Build metadata is
+// ignored. Strings that are not valid versions sort after all valid
+// versions, in lexical order.
+//
+// CompareVersions can be passed to [slices.SortFunc] to sort versions.
+func CompareVersions(a, b string) int {
+	va, aok := parseVersion(a)
+	vb, bok := parseVersion(b)
+	switch {
+	case !aok && !bok:
+		return strings.Compare(a, b)
+	case !aok:
+		return 1
+	case !bok:
+		return -1
+	}
+
+	if c := slices.CompareFunc(va.release, vb.release, compareIdentifier); c != 0 {
+		return c
+	}
+	switch {
+	case va.prerelease == nil && vb.prerelease == nil:
+		return 0
+	case va.prerelease == nil:
+		return 1
+	case vb.prerelease == nil:
+		return -1
+	}
+	return slices.CompareFunc(va.prerelease, vb.prerelease, compareIdentifier)
+}
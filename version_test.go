@@ -0,0 +1,416 @@
+package bcr
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.2.0", "1.10.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0-alpha", "2.0.0", -1},
+		{"1.0.0-rc1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+		{"1.0", "1.0.0", -1},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.2.3.bcr.1", "1.2.3", 1},
+		{"1.2.3.bcr.1", "1.2.3.bcr.2", -1},
+		{"20240722.0", "20230802.1", 1},
+		{"1.0.0", "not a version", -1},
+		{"not a version", "1.0.0", 1},
+		{"bad version a", "bad version b", -1},
+		{"99999999999999999999.0", "100000000000000000000.0", -1},
+		{"2.0.0-dev", "2.0.0", -1},
+		{"2.0.0-pre", "2.0.0", -1},
+		{"2.0.0-beta", "2.0.0-rc1", -1},
+		{"2.0.0-rc1", "1.9.9", 1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := CompareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderingIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     []string // substrings expected in each issue, in order
+	}{
+		{"sorted", []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0-rc1", "2.0.0"}, nil},
+		{"out of order", []string{"1.0.0", "2.0.0", "1.10.0"}, []string{`"1.10.0" at position 2`}},
+		{"duplicate", []string{"1.0.0", "1.1.0", "1.0.0"}, []string{`"1.0.0" is listed more than once (positions 0 and 2)`}},
+		{"prerelease after release", []string{"1.0.0", "1.0.0-rc1"}, []string{`"1.0.0-rc1"`}},
+		{"empty", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Metadata{Versions: tt.versions}
+			got := m.OrderingIssues()
+			if len(got) != len(tt.want) {
+				t.Fatalf("OrderingIssues() = %q, want %d issues", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("issue %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+
+	t.Run("nil safety", func(t *testing.T) {
+		var m *Metadata
+		if got := m.OrderingIssues(); got != nil {
+			t.Errorf("nil.OrderingIssues() = %q, want nil", got)
+		}
+	})
+
+	t.Run("sorting resolves issues", func(t *testing.T) {
+		versions := []string{"2.0.0", "1.0.0", "1.10.0", "1.10.0-rc1"}
+		slices.SortFunc(versions, CompareVersions)
+		m := &Metadata{Versions: versions}
+		if got := m.OrderingIssues(); got != nil {
+			t.Errorf("OrderingIssues() after sort = %q, want nil", got)
+		}
+	})
+}
+
+func TestSortedVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		want     []string
+	}{
+		{"mixed order", []string{"2.0.0", "1.0.0", "1.10.0"}, []string{"1.0.0", "1.10.0", "2.0.0"}},
+		{"numeric not lexical", []string{"1.10.0", "1.9.0", "1.2.0"}, []string{"1.2.0", "1.9.0", "1.10.0"}},
+		{"prereleases", []string{"1.0.0", "1.0.0-rc1", "0.9.0"}, []string{"0.9.0", "1.0.0-rc1", "1.0.0"}},
+		{"non-semver last", []string{"zeta", "1.0.0", "alpha version", "0.1.0"}, []string{"0.1.0", "1.0.0", "alpha version", "zeta"}},
+		{"empty", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Metadata{Versions: tt.versions}
+			orig := slices.Clone(tt.versions)
+			if got := m.SortedVersions(); !slices.Equal(got, tt.want) {
+				t.Errorf("SortedVersions() = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(m.Versions, orig) {
+				t.Errorf("SortedVersions() modified Versions: %v", m.Versions)
+			}
+		})
+	}
+
+	t.Run("nil safety", func(t *testing.T) {
+		var m *Metadata
+		if got := m.SortedVersions(); got != nil {
+			t.Errorf("nil.SortedVersions() = %v, want nil", got)
+		}
+	})
+}
+
+func TestNewest(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		yanked   map[string]string
+		want     string
+	}{
+		{"out of order", []string{"1.10.0", "2.0.0", "1.9.0"}, nil, "2.0.0"},
+		{"yanked", []string{"1.0.0", "2.0.0"}, map[string]string{"2.0.0": "broken"}, "2.0.0"},
+		{"prerelease", []string{"1.0.0", "2.0.0-rc1"}, nil, "2.0.0-rc1"},
+		{"release after its prerelease", []string{"2.0.0", "2.0.0-rc1"}, nil, "2.0.0"},
+		{"empty", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Metadata{Versions: tt.versions, YankedVersions: tt.yanked}
+			if got := m.Newest(); got != tt.want {
+				t.Errorf("Newest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Latest skips the yanked 2.0.0 that Newest reports.
+	m := &Metadata{Versions: []string{"1.0.0", "2.0.0"}, YankedVersions: map[string]string{"2.0.0": "broken"}}
+	if m.Latest() != "1.0.0" || m.Newest() != "2.0.0" {
+		t.Errorf("Latest() = %q, Newest() = %q; want 1.0.0 and 2.0.0", m.Latest(), m.Newest())
+	}
+
+	var nilMeta *Metadata
+	if got := nilMeta.Newest(); got != "" {
+		t.Errorf("nil.Newest() = %q, want empty", got)
+	}
+}
+
+func TestLatestMatching(t *testing.T) {
+	m := &Metadata{
+		Versions: []string{
+			"0.2.1", "0.2.5", "0.3.0",
+			"1.0.0", "1.4.0", "1.4.7", "1.5.0",
+			"1.10.0", "2.0.0-rc1", "2.0.0", "2.1.0",
+		},
+		YankedVersions: map[string]string{"2.1.0": "broken"},
+	}
+
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{">=1.0.0 <2.0.0", "1.10.0"},
+		{">=1.0.0, <2.0.0", "1.10.0"},
+		{">= 1.0.0 < 1.5.0", "1.4.7"},
+		{"~1.4", "1.4.7"},
+		{"~1.4.2", "1.4.7"},
+		{"~1", "1.10.0"},
+		{"^1.4.0", "1.10.0"},
+		{"^0.2.1", "0.2.5"},
+		{"^0.0.3", ""},
+		{"^2.0.0-rc1", "2.0.0"},
+		{"1.4.0", "1.4.0"},
+		{"==1.5.0", "1.5.0"},
+		{">1.0.0 !=1.10.0 <2.0.0-0", "1.5.0"},
+		{"<=1.0.0", "1.0.0"},
+		{"*", "2.0.0"},
+		{"2.1.0", ""}, // yanked
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			got, err := m.LatestMatching(tt.constraint)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("LatestMatching(%q) = %q, %v, want ErrNotFound", tt.constraint, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LatestMatching(%q) error = %v", tt.constraint, err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestMatching(%q) = %q, want %q", tt.constraint, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, constraint := range []string{"", ">=", "~>1.0", ">=banana", "^1.x.0", "1.0.0 ||"} {
+			_, err := m.LatestMatching(constraint)
+			if err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("LatestMatching(%q) error = %v, want malformed constraint error", constraint, err)
+			}
+		}
+	})
+
+	t.Run("nil safety", func(t *testing.T) {
+		var m *Metadata
+		if _, err := m.LatestMatching(">=1.0.0"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestYankedList(t *testing.T) {
+	m := &Metadata{
+		Versions: []string{"1.2.0", "1.10.0", "2.0.0-rc1", "2.0.0", "1.0.0+b", "1.0.0+a"},
+		YankedVersions: map[string]string{
+			"2.0.0":     "regression",
+			"1.10.0":    "bad release",
+			"2.0.0-rc1": "superseded",
+			"1.2.0":     "",
+			"1.0.0+b":   "b",
+			"1.0.0+a":   "a",
+		},
+	}
+	want := []YankedVersion{
+		{"1.0.0+a", "a"},
+		{"1.0.0+b", "b"},
+		{"1.2.0", ""},
+		{"1.10.0", "bad release"},
+		{"2.0.0-rc1", "superseded"},
+		{"2.0.0", "regression"},
+	}
+	for range 10 {
+		if got := m.YankedList(); !slices.Equal(got, want) {
+			t.Fatalf("YankedList() = %v, want %v", got, want)
+		}
+	}
+
+	for name, m := range map[string]*Metadata{"no yanks": {Versions: []string{"1.0.0"}}, "nil": nil} {
+		if got := m.YankedList(); got == nil || len(got) != 0 {
+			t.Errorf("%s: YankedList() = %#v, want empty slice", name, got)
+		}
+	}
+}
+
+func TestAdjacentVersions(t *testing.T) {
+	m := &Metadata{
+		// Registry order is not version order.
+		Versions:       []string{"1.0.0", "1.10.0", "1.2.0", "1.3.0", "2.0.0-rc1", "2.0.0"},
+		YankedVersions: map[string]string{"1.3.0": "broken"},
+	}
+
+	tests := []struct {
+		v              string
+		next, previous string // empty if none
+	}{
+		{"1.0.0", "1.2.0", ""},
+		{"1.2.0", "1.10.0", "1.0.0"}, // skips yanked 1.3.0
+		{"1.3.0", "1.10.0", "1.2.0"}, // a yanked v still has neighbours
+		{"1.10.0", "2.0.0-rc1", "1.2.0"},
+		{"2.0.0", "", "2.0.0-rc1"},
+		{"9.9.9", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.v, func(t *testing.T) {
+			next, ok := m.NextVersion(tt.v)
+			if next != tt.next || ok != (tt.next != "") {
+				t.Errorf("NextVersion(%q) = %q, %v, want %q", tt.v, next, ok, tt.next)
+			}
+			prev, ok := m.PreviousVersion(tt.v)
+			if prev != tt.previous || ok != (tt.previous != "") {
+				t.Errorf("PreviousVersion(%q) = %q, %v, want %q", tt.v, prev, ok, tt.previous)
+			}
+		})
+	}
+
+	t.Run("nil safety", func(t *testing.T) {
+		var m *Metadata
+		if _, ok := m.NextVersion("1.0.0"); ok {
+			t.Error("nil.NextVersion() reported a version")
+		}
+		if _, ok := m.PreviousVersion("1.0.0"); ok {
+			t.Error("nil.PreviousVersion() reported a version")
+		}
+	})
+}
+
+func TestVersionsSince(t *testing.T) {
+	m := &Metadata{
+		Versions:       []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0-rc1", "2.0.0-rc2", "2.0.0", "2.1.0"},
+		YankedVersions: map[string]string{"2.0.0-rc2": "broken"},
+	}
+
+	tests := []struct {
+		baseline string
+		want     []string
+	}{
+		{"1.2.0", []string{"1.10.0", "2.0.0-rc1", "2.0.0", "2.1.0"}},
+		{"2.0.0-rc1", []string{"2.0.0", "2.1.0"}}, // skips yanked rc2
+		{"2.0.0-beta", []string{"2.0.0-rc1", "2.0.0", "2.1.0"}},
+		{"1.5.0", []string{"1.10.0", "2.0.0-rc1", "2.0.0", "2.1.0"}}, // not listed
+		{"0.1.0", []string{"1.0.0", "1.2.0", "1.10.0", "2.0.0-rc1", "2.0.0", "2.1.0"}},
+		{"2.1.0", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseline, func(t *testing.T) {
+			if got := m.VersionsSince(tt.baseline); !slices.Equal(got, tt.want) {
+				t.Errorf("VersionsSince(%q) = %v, want %v", tt.baseline, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nil safety", func(t *testing.T) {
+		var m *Metadata
+		if got := m.VersionsSince("1.0.0"); got != nil {
+			t.Errorf("nil.VersionsSince() = %v, want nil", got)
+		}
+	})
+}
+
+func TestMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    *Metadata
+		wantErr []string // substrings of the error; nil means valid
+	}{
+		{"valid", &Metadata{
+			Versions:       []string{"1.0.0", "1.1.0"},
+			YankedVersions: map[string]string{"1.0.0": "broken"},
+			Maintainers:    []Maintainer{{Name: "Alice"}, {GitHub: "bob"}},
+			Repository:     []string{"github:bazelbuild/rules_go", "gitlab:group/subgroup/repo"},
+		}, nil},
+		{"no versions", &Metadata{}, []string{"no versions"}},
+		{"unknown yanked version", &Metadata{
+			Versions:       []string{"1.0.0"},
+			YankedVersions: map[string]string{"0.9.0": "old", "2.0.0": "typo"},
+		}, []string{`yanked version "0.9.0"`, `yanked version "2.0.0"`}},
+		{"anonymous maintainer", &Metadata{
+			Versions:    []string{"1.0.0"},
+			Maintainers: []Maintainer{{GitHub: "alice"}, {Email: "x@example.com"}},
+		}, []string{"maintainer 1 has neither"}},
+		{"malformed repositories", &Metadata{
+			Versions:   []string{"1.0.0"},
+			Repository: []string{"bazelbuild/rules_go", "github:rules_go", "https://github.com/o/r", "github:o//r"},
+		}, []string{`"bazelbuild/rules_go"`, `"github:rules_go"`, `"https://github.com/o/r"`, `"github:o//r"`}},
+		{"several problems", &Metadata{
+			YankedVersions: map[string]string{"1.0.0": "gone"},
+			Maintainers:    []Maintainer{{}},
+		}, []string{"no versions", `yanked version "1.0.0"`, "maintainer 0"}},
+		{"nil", nil, []string{"no metadata"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.meta.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want errors containing %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRepositoryURLs(t *testing.T) {
+	meta := &Metadata{Repository: []string{
+		"github:bazelbuild/rules_go",
+		"gitlab:group/subgroup/project",
+		"svn:example/repo",
+		"github:missing-repo",
+		"bitbucket:owner/repo",
+	}}
+	want := []string{
+		"https://github.com/bazelbuild/rules_go",
+		"https://gitlab.com/group/subgroup/project",
+		"https://bitbucket.org/owner/repo",
+	}
+	if got := meta.RepositoryURLs(); !slices.Equal(got, want) {
+		t.Errorf("RepositoryURLs() = %v, want %v", got, want)
+	}
+
+	if got := (&Metadata{Repository: []string{"svn:example/repo"}}).RepositoryURLs(); got != nil {
+		t.Errorf("RepositoryURLs() with only unknown hosts = %v, want nil", got)
+	}
+	var nilMeta *Metadata
+	if got := nilMeta.RepositoryURLs(); got != nil {
+		t.Errorf("nil RepositoryURLs() = %v, want nil", got)
+	}
+}